@@ -3,17 +3,100 @@ package config
 import (
 	"fmt"
 	"github.com/caarlos0/env/v8"
+	"time"
 )
 
 type Config struct {
-	Env   string `env:"ENV,required"`
-	PgDSN string `env:"POSTGRES_DSN,required"`
-	Kafka Kafka
+	Env     string `env:"ENV,required"`
+	PgDSN   string `env:"POSTGRES_DSN,required"`
+	Kafka   Kafka
+	Order   Order
+	Cache   Cache
+	Tracing Tracing
+	Health  Health
+}
+
+type Cache struct {
+	Backend string `env:"CACHE_BACKEND" envDefault:"memory"`
+	Redis   Redis
+	L1      L1
+
+	MaxEntries int   `env:"CACHE_MAX_ENTRIES" envDefault:"100000"`
+	MaxBytes   int64 `env:"CACHE_MAX_BYTES" envDefault:"268435456"`
+	Shards     int   `env:"CACHE_SHARDS" envDefault:"16"`
+
+	TTL             time.Duration `env:"CACHE_TTL" envDefault:"24h"`
+	CleanupInterval time.Duration `env:"CACHE_CLEANUP_INTERVAL" envDefault:"1m"`
+
+	WarmUp   WarmUp
+	Snapshot Snapshot
+}
+
+type Redis struct {
+	Addr          string        `env:"CACHE_REDIS_ADDR" envDefault:"redis:6379"`
+	Password      string        `env:"CACHE_REDIS_PASSWORD"`
+	DB            int           `env:"CACHE_REDIS_DB" envDefault:"0"`
+	Prefix        string        `env:"CACHE_REDIS_PREFIX" envDefault:"l0:order:"`
+	Timeout       time.Duration `env:"CACHE_REDIS_TIMEOUT" envDefault:"100ms"`
+	RetryInterval time.Duration `env:"CACHE_REDIS_RETRY_INTERVAL" envDefault:"5s"`
+}
+
+type L1 struct {
+	MaxEntries int           `env:"CACHE_L1_MAX_ENTRIES" envDefault:"10000"`
+	TTL        time.Duration `env:"CACHE_L1_TTL" envDefault:"1m"`
+}
+
+type WarmUp struct {
+	Mode   string        `env:"CACHE_WARMUP" envDefault:"recent"`
+	Limit  int           `env:"CACHE_WARMUP_LIMIT" envDefault:"10000"`
+	Period time.Duration `env:"CACHE_WARMUP_PERIOD" envDefault:"168h"`
+}
+
+type Snapshot struct {
+	Path     string        `env:"CACHE_SNAPSHOT_PATH"`
+	Interval time.Duration `env:"CACHE_SNAPSHOT_INTERVAL" envDefault:"5m"`
+}
+
+type Tracing struct {
+	Exporter    string  `env:"TRACING_EXPORTER" envDefault:"none"`
+	Endpoint    string  `env:"TRACING_OTLP_ENDPOINT" envDefault:"otel-collector:4318"`
+	Insecure    bool    `env:"TRACING_OTLP_INSECURE" envDefault:"true"`
+	File        string  `env:"TRACING_FILE" envDefault:"traces.jsonl"`
+	SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
+}
+
+type Health struct {
+	CheckTimeout   time.Duration `env:"HEALTH_CHECK_TIMEOUT" envDefault:"2s"`
+	MaxConsumerLag int64         `env:"HEALTH_MAX_CONSUMER_LAG" envDefault:"0"`
+}
+
+type Order struct {
+	ConflictPolicy string        `env:"ORDER_CONFLICT_POLICY" envDefault:"reject"`
+	NotFoundTTL    time.Duration `env:"ORDER_NOT_FOUND_TTL" envDefault:"5s"`
 }
 
 type Kafka struct {
-	Host  string `env:"BROKER_HOST,required"`
-	Topic string `env:"BROKER_TOPIC,required"`
+	Host              string `env:"BROKER_HOST,required"`
+	Topic             string `env:"BROKER_TOPIC,required"`
+	DLQTopic          string `env:"BROKER_DLQ_TOPIC" envDefault:"orders-dlq"`
+	InvalidationTopic string `env:"BROKER_INVALIDATION_TOPIC" envDefault:"orders-cache-invalidation"`
+	GroupID           string `env:"BROKER_GROUP_ID" envDefault:"orders-consumer"`
+	Workers           int    `env:"BROKER_WORKERS" envDefault:"4"`
+	Batch             Batch
+	Retry             Retry
+}
+
+type Batch struct {
+	Size    int           `env:"BROKER_BATCH_SIZE" envDefault:"100"`
+	Timeout time.Duration `env:"BROKER_BATCH_TIMEOUT" envDefault:"100ms"`
+}
+
+type Retry struct {
+	LogEvery        int           `env:"BROKER_RETRY_LOG_EVERY" envDefault:"5"`
+	InitialInterval time.Duration `env:"BROKER_RETRY_INITIAL_INTERVAL" envDefault:"100ms"`
+	MaxInterval     time.Duration `env:"BROKER_RETRY_MAX_INTERVAL" envDefault:"5s"`
+	Multiplier      float64       `env:"BROKER_RETRY_MULTIPLIER" envDefault:"2"`
+	Jitter          float64       `env:"BROKER_RETRY_JITTER" envDefault:"0.2"`
 }
 
 // MustLoad loads configuration from config.yaml
@@ -2,23 +2,35 @@ package app
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/gofiber/contrib/fiberzap/v2"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"go.uber.org/zap"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
+	"time"
 	"wb-internship-l0/config"
 	"wb-internship-l0/internal/broker"
+	"wb-internship-l0/internal/controller/http/probes"
+	"wb-internship-l0/internal/controller/http/problem"
 	v1 "wb-internship-l0/internal/controller/http/v1"
+	"wb-internship-l0/internal/controller/http/web"
 	database "wb-internship-l0/internal/lib/pg"
+	"wb-internship-l0/internal/lib/tracing"
+	"wb-internship-l0/internal/metrics"
 	"wb-internship-l0/internal/repository"
 	"wb-internship-l0/internal/service"
 	"wb-internship-l0/pkg/cache"
 	"wb-internship-l0/pkg/logger"
 )
 
+// tracesFlushTimeout bounds how long pending spans are exported on shutdown.
+const tracesFlushTimeout = 5 * time.Second
+
 func Run() {
 	// Config init
 	cfg := config.MustLoad()
@@ -29,14 +41,56 @@ func Run() {
 	// Context
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// Tracing init
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatal("Failed to set up tracing",
+			zap.Error(err),
+		)
+	}
+
 	// Database init
 	log.Info("Database initialization...")
 	pg := database.NewPostgres(ctx, log, cfg.PgDSN)
 	log.Info("Database initialization: OK.")
 
+	// Metrics init
+	prom := metrics.New()
+	prom.RegisterPool(pg.DB)
+
 	// Cache init
 	log.Info("Cache initialization...")
-	memoryCache := cache.NewMemoryCache()
+	// Instances using the tiered cache drop keys deleted by each other from their L1
+	var (
+		invalidator *broker.KafkaCacheInvalidator
+		onDelete    func(string)
+	)
+	if cfg.Cache.Backend == cacheBackendTiered {
+		invalidator = broker.NewKafkaCacheInvalidator(log, []string{cfg.Kafka.Host}, cfg.Kafka.InvalidationTopic)
+		onDelete = invalidator.Publish
+	}
+	memoryCache, err := newOrderCache(log, cfg.Cache, onDelete)
+	if err != nil {
+		log.Fatal("Invalid configuration",
+			zap.Error(err),
+		)
+	}
+	if tiered, ok := memoryCache.(*cache.TieredCache[string, json.RawMessage]); ok && invalidator != nil {
+		go func() {
+			if err := invalidator.Listen(ctx, tiered.Invalidate); err != nil {
+				log.Error("Cache invalidation listener stopped with error",
+					zap.Error(err),
+				)
+			}
+		}()
+	}
+	prom.RegisterCache(memoryCache)
 	log.Info("Cache initialization: OK.")
 
 	// Repositories init
@@ -46,22 +100,60 @@ func Run() {
 
 	// Services init
 	log.Info("Services initialization...")
+	conflict, err := service.ParseConflictPolicy(cfg.Order.ConflictPolicy)
+	if err != nil {
+		log.Fatal("Invalid configuration",
+			zap.Error(err),
+		)
+	}
 	deps := service.ServicesDependencies{
-		Log:   log,
-		Cache: memoryCache,
-		Repos: repositories,
+		Log:      log,
+		Cache:    metrics.InstrumentCache(prom, memoryCache),
+		Repos:    repositories,
+		Conflict: conflict,
+
+		NotFoundTTL: cfg.Order.NotFoundTTL,
 	}
 	services := service.NewServices(deps)
 	log.Info("Services initialization: OK.")
 
-	// Restore cache
-	log.Info("Restoring cache...")
-	err := services.Order.LoadOrdersToCache(ctx)
+	// Restore cache in background, so the server doesn't wait for it.
+	// Snapshots start once the cache is restored, so a partial cache never replaces a snapshot.
+	warmUpMode, err := service.ParseWarmUpMode(cfg.Cache.WarmUp.Mode)
 	if err != nil {
-		log.Warn("Failed to restore cache",
+		log.Fatal("Invalid configuration",
 			zap.Error(err),
 		)
 	}
+	warmUp := service.WarmUpPolicy{
+		Mode:   warmUpMode,
+		Limit:  cfg.Cache.WarmUp.Limit,
+		Period: cfg.Cache.WarmUp.Period,
+	}
+	// A shared Redis cache outlives the service, so it isn't snapshotted.
+	snapshot := cfg.Cache.Snapshot
+	if cfg.Cache.Backend != cacheBackendMemory {
+		snapshot.Path = ""
+	}
+	// The service becomes ready once the cache is restored, even if only partially:
+	// missing orders are read through from the database.
+	var cacheRestored atomic.Bool
+	snapshots := make(chan *cache.Snapshotter[string, json.RawMessage], 1)
+	go func() {
+		log.Info("Restoring cache...")
+		err := restoreOrderCache(ctx, log, snapshot, memoryCache, services.Order, warmUp)
+		if err != nil {
+			log.Warn("Failed to restore cache",
+				zap.Error(err),
+			)
+		}
+		cacheRestored.Store(true)
+		if ctx.Err() != nil {
+			snapshots <- nil
+			return
+		}
+		snapshots <- startOrderCacheSnapshots(log, snapshot, memoryCache)
+	}()
 
 	// Channel for signals
 	quit := make(chan os.Signal, 1)
@@ -69,8 +161,31 @@ func Run() {
 
 	// Broker init
 	log.Info("Kafka reader initialization...")
-	kafka := broker.NewKafkaConsumer(log, services, []string{cfg.Kafka.Host}, cfg.Kafka.Topic)
+	dlq := broker.NewKafkaDeadLetterPublisher(log, []string{cfg.Kafka.Host}, cfg.Kafka.DLQTopic)
+	kafka, err := broker.NewKafkaConsumer(log, services, dlq, broker.KafkaConsumerConfig{
+		Brokers:      []string{cfg.Kafka.Host},
+		Topic:        cfg.Kafka.Topic,
+		GroupID:      cfg.Kafka.GroupID,
+		Workers:      cfg.Kafka.Workers,
+		BatchSize:    cfg.Kafka.Batch.Size,
+		BatchTimeout: cfg.Kafka.Batch.Timeout,
+		Retry: broker.RetryPolicy{
+			InitialInterval: cfg.Kafka.Retry.InitialInterval,
+			MaxInterval:     cfg.Kafka.Retry.MaxInterval,
+			Multiplier:      cfg.Kafka.Retry.Multiplier,
+			Jitter:          cfg.Kafka.Retry.Jitter,
+		},
+		RetryLogEvery: cfg.Kafka.Retry.LogEvery,
+		Metrics:       prom,
+	})
+	if err != nil {
+		log.Fatal("Invalid configuration",
+			zap.Error(err),
+		)
+	}
+	consumerDone := make(chan struct{})
 	go func() {
+		defer close(consumerDone)
 		if err := kafka.Listen(ctx); err != nil {
 			log.Error("Kafka consumer stopped with error",
 				zap.Error(err),
@@ -81,13 +196,22 @@ func Run() {
 	// Router init
 	log.Info("Router initialization...")
 	app := fiber.New(fiber.Config{
-		AppName: "WB-INTERNSHIP-L0",
+		AppName:      "WB-INTERNSHIP-L0",
+		ErrorHandler: problem.Handler(log),
 	})
+	app.Use(requestid.New())
 	app.Use(recover.New())
+	app.Use(prom.Middleware())
+	app.Use(tracing.Middleware())
 	app.Use(fiberzap.New(fiberzap.Config{
-		Logger: log,
+		Logger:   log,
+		Fields:   []string{"ip", "latency", "status", "method", "url", "requestId"},
+		SkipURIs: []string{probes.LivenessPath, probes.ReadinessPath},
 	}))
+	app.Get("/metrics", prom.Handler())
+	probes.InitRouter(log, app, newReadinessChecker(cfg.Health, pg, kafka, invalidator, &cacheRestored))
 	v1.InitRouter(log, app, services)
+	web.InitRouter(app)
 	go func() {
 		if err := app.Listen(":3000"); err != nil {
 			log.Error("Fiber server error",
@@ -101,11 +225,28 @@ func Run() {
 	log.Info("Shutdown signal received")
 	cancel()
 
+	// Workers must finish their batches before the reader and the DLQ writer are closed.
+	<-consumerDone
+
 	err = kafka.Shutdown()
 	if err != nil {
 		log.Error("Failed to close Kafka")
 	}
 
+	if invalidator != nil {
+		if err := invalidator.Close(); err != nil {
+			log.Error("Failed to close cache invalidator",
+				zap.Error(err),
+			)
+		}
+	}
+
+	if err := dlq.Close(); err != nil {
+		log.Error("Failed to close DLQ writer",
+			zap.Error(err),
+		)
+	}
+
 	if err := app.Shutdown(); err != nil {
 		log.Error("Error shutting down Fiber",
 			zap.Error(err),
@@ -114,6 +255,28 @@ func Run() {
 		log.Info("Fiber server stopped")
 	}
 
+	if snapshotter := <-snapshots; snapshotter != nil {
+		if n, err := snapshotter.Stop(); err != nil {
+			log.Error("Failed to write cache snapshot",
+				zap.Error(err),
+			)
+		} else {
+			log.Info("Cache snapshot written",
+				zap.Int("entries", n),
+			)
+		}
+	}
+
+	memoryCache.Close()
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), tracesFlushTimeout)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		log.Error("Failed to flush traces",
+			zap.Error(err),
+		)
+	}
+
 	log.Info("Gracefully stopped")
 
 }
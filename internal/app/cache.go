@@ -0,0 +1,166 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"os"
+	"time"
+	"wb-internship-l0/config"
+	"wb-internship-l0/internal/service"
+	"wb-internship-l0/pkg/cache"
+)
+
+// Cache backends selected by CACHE_BACKEND.
+const (
+	cacheBackendMemory = "memory"
+	cacheBackendRedis  = "redis"
+	cacheBackendTiered = "tiered"
+)
+
+// snapshotSkew is subtracted from the snapshot watermark to cover the clock
+// difference between the service and the database.
+const snapshotSkew = time.Minute
+
+// newOrderCache builds the order cache described by the configuration.
+// The Redis backend falls back to the local cache while the server is unavailable.
+// The tiered backend keeps a local L1 in front of Redis and calls onDelete for every
+// deleted key, so that other instances can drop it from their L1.
+func newOrderCache(log *zap.Logger, cfg config.Cache, onDelete func(string)) (cache.Cache[string, json.RawMessage], error) {
+	const op = "app.newOrderCache"
+
+	switch cfg.Backend {
+	case cacheBackendMemory:
+		return newLocalOrderCache(cfg), nil
+	case cacheBackendRedis:
+		return newRedisOrderCache(log, cfg, newLocalOrderCache(cfg)), nil
+	case cacheBackendTiered:
+		l1 := cfg
+		l1.MaxEntries = cfg.L1.MaxEntries
+		l1.TTL = cfg.L1.TTL
+
+		return cache.NewTieredCache(
+			newLocalOrderCache(l1, cache.WithFixedTTL()),
+			newRedisOrderCache(log, cfg, nil),
+			onDelete,
+		), nil
+	default:
+		return nil, fmt.Errorf("%s: unknown cache backend %q", op, cfg.Backend)
+	}
+}
+
+// newRedisOrderCache builds the order cache stored in Redis.
+func newRedisOrderCache(
+	log *zap.Logger,
+	cfg config.Cache,
+	fallback cache.Cache[string, json.RawMessage],
+) cache.Cache[string, json.RawMessage] {
+	const op = "app.newRedisOrderCache"
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+
+	return cache.NewRedisCache(client, cache.RedisConfig{
+		Prefix:        cfg.Redis.Prefix,
+		Timeout:       cfg.Redis.Timeout,
+		RetryInterval: cfg.Redis.RetryInterval,
+		OnError: func(err error) {
+			log.Warn("Redis cache is unavailable",
+				zap.String("op", op),
+				zap.Error(err),
+			)
+		},
+	}, cache.BytesCodec[json.RawMessage](), fallback, cache.WithDefaultTTL(cfg.TTL))
+}
+
+// newLocalOrderCache builds the in-memory order cache.
+// With several shards the size limits are split evenly between them.
+func newLocalOrderCache(cfg config.Cache, extra ...cache.Option) cache.Cache[string, json.RawMessage] {
+	opts := append([]cache.Option{
+		cache.WithDefaultTTL(cfg.TTL),
+		cache.WithCleanupInterval(cfg.CleanupInterval),
+	}, extra...)
+
+	shards := cache.ShardCount(cfg.Shards)
+	maxEntries := ceilDiv(cfg.MaxEntries, shards)
+	maxBytes := ceilDiv(cfg.MaxBytes, int64(shards))
+
+	newShard := func() cache.Cache[string, json.RawMessage] {
+		if maxEntries > 0 || maxBytes > 0 {
+			return cache.NewLRUCache[string, json.RawMessage](maxEntries, maxBytes, opts...)
+		}
+
+		return cache.NewMemoryCache[string, json.RawMessage](opts...)
+	}
+
+	if shards == 1 {
+		return newShard()
+	}
+
+	return cache.NewShardedCache(shards, cache.StringHasher(), newShard)
+}
+
+// restoreOrderCache fills the cache from the snapshot and the orders changed after it.
+// Without a usable snapshot the cache is warmed up from the database according to the policy.
+func restoreOrderCache(
+	ctx context.Context,
+	log *zap.Logger,
+	cfg config.Snapshot,
+	c cache.Cache[string, json.RawMessage],
+	orders service.Order,
+	warmUp service.WarmUpPolicy,
+) error {
+	if cfg.Path != "" {
+		watermark, loaded, err := cache.LoadSnapshot(cfg.Path, c)
+		if err == nil {
+			log.Info("Cache snapshot loaded",
+				zap.Int("entries", loaded),
+				zap.Time("watermark", watermark),
+			)
+
+			return orders.RefreshOrdersSince(ctx, watermark.Add(-snapshotSkew))
+		}
+
+		if errors.Is(err, os.ErrNotExist) {
+			log.Info("Cache snapshot not found",
+				zap.String("path", cfg.Path),
+			)
+		} else {
+			log.Warn("Failed to load cache snapshot",
+				zap.String("path", cfg.Path),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return orders.LoadOrdersToCache(ctx, warmUp)
+}
+
+// startOrderCacheSnapshots starts periodic snapshots of the cache.
+// Returns nil if snapshots are disabled.
+func startOrderCacheSnapshots(
+	log *zap.Logger,
+	cfg config.Snapshot,
+	c cache.Cache[string, json.RawMessage],
+) *cache.Snapshotter[string, json.RawMessage] {
+	if cfg.Path == "" {
+		return nil
+	}
+
+	return cache.StartSnapshots(c, cfg.Path, cfg.Interval, func(err error) {
+		log.Warn("Failed to write cache snapshot",
+			zap.String("path", cfg.Path),
+			zap.Error(err),
+		)
+	})
+}
+
+func ceilDiv[T int | int64](a, b T) T {
+	return (a + b - 1) / b
+}
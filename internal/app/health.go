@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"wb-internship-l0/config"
+	"wb-internship-l0/internal/broker"
+	"wb-internship-l0/internal/lib/health"
+	database "wb-internship-l0/internal/lib/pg"
+)
+
+// newReadinessChecker composes the readiness of the service from its dependencies.
+// The service is ready once the cache is restored, Postgres and Kafka are reachable and,
+// with the tiered cache, invalidations from other instances are received.
+// The invalidator may be nil.
+func newReadinessChecker(
+	cfg config.Health,
+	pg *database.Postgres,
+	consumer *broker.KafkaConsumer,
+	invalidator *broker.KafkaCacheInvalidator,
+	cacheRestored *atomic.Bool,
+) *health.Checker {
+	checker := health.NewChecker(cfg.CheckTimeout)
+
+	checker.Register("postgres", pg.Ping)
+
+	checker.Register("kafka", func(ctx context.Context) error {
+		if err := consumer.Ping(ctx); err != nil {
+			return err
+		}
+
+		if lag := consumer.Lag(); cfg.MaxConsumerLag > 0 && lag > cfg.MaxConsumerLag {
+			return fmt.Errorf("consumer lag %d exceeds %d", lag, cfg.MaxConsumerLag)
+		}
+
+		return nil
+	})
+
+	checker.Register("cache", func(context.Context) error {
+		if !cacheRestored.Load() {
+			return errors.New("cache warm-up in progress")
+		}
+
+		return nil
+	})
+
+	if invalidator != nil {
+		checker.Register("cache_invalidation", func(context.Context) error {
+			return invalidator.Err()
+		})
+	}
+
+	return checker
+}
@@ -0,0 +1,168 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Failure reasons attached to dead-lettered messages.
+const (
+	ReasonUnmarshal    = "unmarshal_failed"
+	ReasonValidation   = "validation_failed"
+	ReasonMissingField = "missing_field"
+	ReasonSave         = "save_failed"
+	ReasonConflict     = "order_conflict"
+	ReasonNotFound     = "order_not_found"
+	ReasonTransition   = "invalid_transition"
+)
+
+// Headers added to every dead-lettered message.
+const (
+	HeaderDLQReason          = "x-dlq-reason"
+	HeaderDLQError           = "x-dlq-error"
+	HeaderDLQOp              = "x-dlq-op"
+	HeaderDLQSourceTopic     = "x-dlq-source-topic"
+	HeaderDLQSourcePartition = "x-dlq-source-partition"
+	HeaderDLQSourceOffset    = "x-dlq-source-offset"
+	HeaderDLQSourceTimestamp = "x-dlq-source-timestamp"
+	HeaderDLQFailedAt        = "x-dlq-failed-at"
+	HeaderDLQViolations      = "x-dlq-violations"
+)
+
+// DeadLetter describes a message rejected by the consumer.
+type DeadLetter struct {
+	Message kafka.Message
+	Reason  string
+	Op      string
+	Err     error
+}
+
+// DeadLetterPublisher defines an interface for forwarding rejected messages.
+type DeadLetterPublisher interface {
+	Publish(ctx context.Context, letter DeadLetter) error
+	Close() error
+}
+
+// KafkaDeadLetterPublisher is an implementation of the DeadLetterPublisher interface for Kafka.
+type KafkaDeadLetterPublisher struct {
+	log    *zap.Logger
+	writer *kafka.Writer
+}
+
+// NewKafkaDeadLetterPublisher returns a new instance of KafkaDeadLetterPublisher writing to the given topic.
+func NewKafkaDeadLetterPublisher(log *zap.Logger, brokers []string, topic string) *KafkaDeadLetterPublisher {
+	return &KafkaDeadLetterPublisher{
+		log: log,
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Topic:                  topic,
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+		},
+	}
+}
+
+// Publish writes the original payload of a rejected message to the DLQ topic.
+// The original key is kept and failure details are attached as headers.
+func (p *KafkaDeadLetterPublisher) Publish(ctx context.Context, letter DeadLetter) error {
+	const op = "broker.KafkaDeadLetterPublisher.Publish"
+
+	msg := kafka.Message{
+		Key:     letter.Message.Key,
+		Value:   letter.Message.Value,
+		Headers: deadLetterHeaders(letter),
+	}
+
+	if err := p.writer.WriteMessages(ctx, msg); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	p.log.Info("Message forwarded to DLQ",
+		zap.String("op", op),
+		zap.String("reason", letter.Reason),
+		zap.Int("Partition", letter.Message.Partition),
+		zap.Int64("Offset", letter.Message.Offset),
+	)
+
+	return nil
+}
+
+// Close flushes pending messages and closes the DLQ writer.
+func (p *KafkaDeadLetterPublisher) Close() error {
+	const op = "broker.KafkaDeadLetterPublisher.Close"
+
+	if err := p.writer.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// isPermanentPublishError reports whether publishing failed because of the message itself,
+// e.g. it exceeds the size limit, so that publishing it again can never succeed.
+func isPermanentPublishError(err error) bool {
+	var tooLarge kafka.MessageTooLargeError
+	if errors.As(err, &tooLarge) {
+		return true
+	}
+
+	// WriteErrors holds an error per written message and doesn't unwrap to them.
+	var writeErrs kafka.WriteErrors
+	if errors.As(err, &writeErrs) {
+		for _, writeErr := range writeErrs {
+			if writeErr != nil && isPermanentPublishError(writeErr) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	var kafkaErr kafka.Error
+	if errors.As(err, &kafkaErr) {
+		switch kafkaErr {
+		case kafka.MessageSizeTooLarge, kafka.RecordListTooLarge, kafka.InvalidRecord, kafka.InvalidTimestamp:
+			return true
+		}
+	}
+
+	return false
+}
+
+// deadLetterHeaders keeps the original headers and appends the failure details.
+func deadLetterHeaders(letter DeadLetter) []kafka.Header {
+	src := letter.Message
+
+	headers := make([]kafka.Header, 0, len(src.Headers)+9)
+	headers = append(headers, src.Headers...)
+
+	errMsg := ""
+	if letter.Err != nil {
+		errMsg = letter.Err.Error()
+	}
+
+	headers = append(headers,
+		kafka.Header{Key: HeaderDLQReason, Value: []byte(letter.Reason)},
+		kafka.Header{Key: HeaderDLQError, Value: []byte(errMsg)},
+		kafka.Header{Key: HeaderDLQOp, Value: []byte(letter.Op)},
+		kafka.Header{Key: HeaderDLQSourceTopic, Value: []byte(src.Topic)},
+		kafka.Header{Key: HeaderDLQSourcePartition, Value: []byte(strconv.Itoa(src.Partition))},
+		kafka.Header{Key: HeaderDLQSourceOffset, Value: []byte(strconv.FormatInt(src.Offset, 10))},
+		kafka.Header{Key: HeaderDLQSourceTimestamp, Value: []byte(src.Time.Format(time.RFC3339Nano))},
+		kafka.Header{Key: HeaderDLQFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339Nano))},
+	)
+
+	var violations Violations
+	if errors.As(letter.Err, &violations) {
+		headers = append(headers, kafka.Header{Key: HeaderDLQViolations, Value: []byte(strings.Join(violations.Rules(), ","))})
+	}
+
+	return headers
+}
@@ -0,0 +1,148 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+	"wb-internship-l0/internal/schema"
+	"wb-internship-l0/internal/service"
+)
+
+// HeaderEventType is the message header that holds the event type.
+// Messages without it are treated as EventOrderCreated.
+const HeaderEventType = "x-event-type"
+
+// Event types accepted by the consumer.
+const (
+	EventOrderCreated = "order.created"
+	EventOrderUpdated = "order.updated"
+)
+
+// eventType returns the event type of a message.
+func eventType(msg kafka.Message) string {
+	for _, h := range msg.Headers {
+		if h.Key == HeaderEventType {
+			return string(h.Value)
+		}
+	}
+
+	return EventOrderCreated
+}
+
+// handleUpdate applies an "order updated" event.
+// It returns true if the message is done with and its offset may be committed.
+func (k *KafkaConsumer) handleUpdate(ctx context.Context, msg kafka.Message) bool {
+	const op = "broker.KafkaConsumer.handleUpdate"
+
+	k.log.Info("Order update received from Kafka",
+		zap.Int("Partition", msg.Partition),
+		zap.Int64("Offset", msg.Offset),
+	)
+
+	var update OrderUpdate
+
+	if err := json.Unmarshal(msg.Value, &update); err != nil {
+		k.log.Error("Failed to unmarshal data",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+
+		return k.reject(ctx, msg, ReasonUnmarshal, op, err)
+	}
+
+	if err := validateUpdate(update, k.rules); err != nil {
+		k.log.Error("Invalid data",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+
+		return k.reject(ctx, msg, rejectReason(err), op, err)
+	}
+
+	status, err := service.ParseOrderStatus(update.Status)
+	if err != nil {
+		k.log.Error("Invalid data",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+
+		return k.reject(ctx, msg, ReasonValidation, op, err)
+	}
+
+	err = k.retryTransient(ctx, func(ctx context.Context) error {
+		return k.service.UpdateOrder(ctx, update.OrderUID, status, update.Order)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			k.log.Info("Consumer context canceled, message left uncommitted",
+				zap.Int("Partition", msg.Partition),
+				zap.Int64("Offset", msg.Offset),
+			)
+
+			return false
+		}
+
+		return k.rejectSaveErr(ctx, msg, err)
+	}
+
+	k.metrics.MessageProcessed(EventOrderUpdated)
+
+	return true
+}
+
+// validateUpdate checks the update and the full order document, if it is present.
+func validateUpdate(update OrderUpdate, rules Rules) error {
+	const op = "broker.validateUpdate"
+
+	if err := validator.New().Struct(update); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(update.Order) == 0 {
+		return nil
+	}
+
+	if err := schema.ValidateOrder(update.Order); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var order Order
+
+	if err := json.Unmarshal(update.Order, &order); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := rules.Check(order); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if order.OrderUID != update.OrderUID {
+		return fmt.Errorf("%s: order_uid %q doesn't match the updated order %q", op, order.OrderUID, update.OrderUID)
+	}
+
+	return nil
+}
+
+// rejectReason returns the DLQ reason of an invalid message. Malformed JSON, missing fields,
+// broken rules and other validation errors are told apart.
+func rejectReason(err error) string {
+	if errors.Is(err, schema.ErrInvalidJSON) {
+		return ReasonUnmarshal
+	}
+
+	var schemaErr *schema.ValidationError
+	if errors.As(err, &schemaErr) && len(schemaErr.Missing) > 0 {
+		return ReasonMissingField
+	}
+
+	var violations Violations
+	if errors.As(err, &violations) {
+		return violations.Reason()
+	}
+
+	return ReasonValidation
+}
@@ -0,0 +1,62 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"sync"
+)
+
+// lagTracker remembers how far behind the end of every partition the consumer is.
+type lagTracker struct {
+	mu  sync.Mutex
+	lag map[int]int64
+}
+
+func newLagTracker() *lagTracker {
+	return &lagTracker{
+		lag: make(map[int]int64),
+	}
+}
+
+func (t *lagTracker) set(partition int, lag int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lag[partition] = lag
+}
+
+func (t *lagTracker) total() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var total int64
+	for _, lag := range t.lag {
+		total += lag
+	}
+
+	return total
+}
+
+// Ping checks that at least one of the brokers accepts connections.
+func (k *KafkaConsumer) Ping(ctx context.Context) error {
+	const op = "broker.KafkaConsumer.Ping"
+
+	var errs []error
+	for _, broker := range k.brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			return conn.Close()
+		}
+		errs = append(errs, err)
+	}
+
+	return fmt.Errorf("%s: %w", op, errors.Join(errs...))
+}
+
+// Lag returns the total number of messages behind the end of the partitions,
+// as of the last message fetched from each of them.
+func (k *KafkaConsumer) Lag() int64 {
+	return k.lag.total()
+}
@@ -0,0 +1,172 @@
+package broker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// HeaderInvalidationOrigin identifies the instance that published an invalidation,
+// so that it can skip its own messages.
+const HeaderInvalidationOrigin = "x-cache-origin"
+
+const (
+	// invalidationBatchTimeout bounds how long an invalidation waits in the writer.
+	invalidationBatchTimeout = 10 * time.Millisecond
+	// invalidationMaxWait bounds how long the reader waits for new invalidations.
+	invalidationMaxWait = 100 * time.Millisecond
+	// invalidationRetryInterval is the pause after a failed read of invalidations.
+	invalidationRetryInterval = time.Second
+)
+
+var errInvalidationNotListening = errors.New("cache invalidation listener is not running")
+
+// KafkaCacheInvalidator broadcasts deleted cache keys to every instance over a Kafka topic.
+// Every instance reads the whole topic from its end, so the topic must have a single partition.
+type KafkaCacheInvalidator struct {
+	log    *zap.Logger
+	origin string
+	writer *kafka.Writer
+	reader *kafka.Reader
+
+	mu      sync.Mutex
+	readErr error
+}
+
+// NewKafkaCacheInvalidator returns a new instance of KafkaCacheInvalidator using the given topic.
+func NewKafkaCacheInvalidator(log *zap.Logger, brokers []string, topic string) *KafkaCacheInvalidator {
+	const op = "broker.KafkaCacheInvalidator.Publish"
+
+	return &KafkaCacheInvalidator{
+		log:     log,
+		origin:  newInstanceID(),
+		readErr: errInvalidationNotListening,
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Topic:                  topic,
+			BatchTimeout:           invalidationBatchTimeout,
+			Async:                  true,
+			AllowAutoTopicCreation: true,
+			Completion: func(messages []kafka.Message, err error) {
+				if err != nil {
+					log.Error("Failed to publish cache invalidation",
+						zap.String("op", op),
+						zap.Int("keys", len(messages)),
+						zap.Error(err),
+					)
+				}
+			},
+		},
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers: brokers,
+			Topic:   topic,
+			MaxWait: invalidationMaxWait,
+		}),
+	}
+}
+
+// Publish asynchronously notifies other instances that the key was deleted.
+func (i *KafkaCacheInvalidator) Publish(key string) {
+	// Errors of asynchronous writes are reported by the writer completion.
+	_ = i.writer.WriteMessages(context.Background(), kafka.Message{
+		Key:     []byte(key),
+		Headers: []kafka.Header{{Key: HeaderInvalidationOrigin, Value: []byte(i.origin)}},
+	})
+}
+
+// Listen calls invalidate for every key deleted by other instances until the context is canceled.
+// Invalidations published before Listen is called are skipped. Failed reads are logged and
+// retried after a pause; meanwhile Err reports the failure.
+func (i *KafkaCacheInvalidator) Listen(ctx context.Context, invalidate func(key string)) error {
+	const op = "broker.KafkaCacheInvalidator.Listen"
+
+	if err := i.reader.SetOffset(kafka.LastOffset); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	i.setReadErr(nil)
+	defer i.setReadErr(errInvalidationNotListening)
+
+	for {
+		msg, err := i.reader.ReadMessage(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+				return nil
+			}
+
+			i.log.Error("Failed to read cache invalidations, retrying",
+				zap.String("op", op),
+				zap.Error(err),
+			)
+			i.setReadErr(err)
+
+			timer := time.NewTimer(invalidationRetryInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+			}
+
+			continue
+		}
+		i.setReadErr(nil)
+
+		if invalidationOrigin(msg) == i.origin {
+			continue
+		}
+
+		invalidate(string(msg.Key))
+	}
+}
+
+// Err returns why invalidations from other instances are not being received:
+// Listen is not running or its last read failed. It returns nil while they are received.
+func (i *KafkaCacheInvalidator) Err() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.readErr
+}
+
+func (i *KafkaCacheInvalidator) setReadErr(err error) {
+	i.mu.Lock()
+	i.readErr = err
+	i.mu.Unlock()
+}
+
+// Close flushes pending invalidations and closes the writer and the reader.
+func (i *KafkaCacheInvalidator) Close() error {
+	const op = "broker.KafkaCacheInvalidator.Close"
+
+	if err := errors.Join(i.writer.Close(), i.reader.Close()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// invalidationOrigin returns the instance that published the invalidation.
+func invalidationOrigin(msg kafka.Message) string {
+	for _, h := range msg.Headers {
+		if h.Key == HeaderInvalidationOrigin {
+			return string(h.Value)
+		}
+	}
+
+	return ""
+}
+
+// newInstanceID returns a random identifier of the running instance.
+func newInstanceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -4,10 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"github.com/go-playground/validator/v10"
+	"fmt"
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"sync"
 	"time"
+	"wb-internship-l0/internal/entity"
+	"wb-internship-l0/internal/lib/tracing"
+	"wb-internship-l0/internal/schema"
 	"wb-internship-l0/internal/service"
 )
 
@@ -17,88 +24,521 @@ type Consumer interface {
 	Shutdown() error
 }
 
+// KafkaConsumerConfig holds the settings of KafkaConsumer.
+type KafkaConsumerConfig struct {
+	Brokers []string
+	Topic   string
+	GroupID string
+	Workers int
+	Retry   RetryPolicy
+
+	// RetryLogEvery is how many failed attempts to retry a transient failure are made
+	// between error logs. Zero disables the logs.
+	RetryLogEvery int
+
+	// BatchSize and BatchTimeout limit how many messages a worker saves at once
+	// and how long it waits to fill a batch.
+	BatchSize    int
+	BatchTimeout time.Duration
+
+	// Metrics is optional.
+	Metrics Metrics
+
+	// Rules are checked against every order after field validation. Defaults to DefaultRules.
+	Rules Rules
+}
+
 // KafkaConsumer is an implementation of the Consumer interface for Kafka.
+//
+// Messages are fetched by a single loop and dispatched to a fixed pool of workers.
+// All messages of a partition go to the same worker, so ordering within a partition
+// is preserved while different partitions are processed concurrently.
 type KafkaConsumer struct {
 	log     *zap.Logger
+	brokers []string
 	reader  *kafka.Reader
+	dlq     DeadLetterPublisher
+	retry   RetryPolicy
+	workers int
+	offsets *offsetTracker
+	lag     *lagTracker
+	metrics Metrics
+	rules   Rules
+
+	retryLogEvery int
+	batchSize     int
+	batchTimeout  time.Duration
+
 	service service.Order
 }
 
 // NewKafkaConsumer return a new instance of KafkaConsumer with the given configuration.
-func NewKafkaConsumer(log *zap.Logger, services *service.Services, brokers []string, topic string) *KafkaConsumer {
+// Returns an error if the retry policy is invalid.
+func NewKafkaConsumer(log *zap.Logger, services *service.Services, dlq DeadLetterPublisher, cfg KafkaConsumerConfig) (*KafkaConsumer, error) {
+	const op = "broker.NewKafkaConsumer"
+
+	if err := cfg.Retry.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	workers := cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	batchSize := cfg.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	metrics := cfg.Metrics
+	if metrics == nil {
+		metrics = noopMetrics{}
+	}
+
+	rules := cfg.Rules
+	if rules == nil {
+		rules = DefaultRules()
+	}
+
 	return &KafkaConsumer{
-		log: log,
+		log:     log,
+		brokers: cfg.Brokers,
 		reader: kafka.NewReader(kafka.ReaderConfig{
-			Brokers: brokers,
-			Topic:   topic,
+			Brokers: cfg.Brokers,
+			Topic:   cfg.Topic,
+			GroupID: cfg.GroupID,
 		}),
+		dlq:     dlq,
+		retry:   cfg.Retry,
+		workers: workers,
+		offsets: newOffsetTracker(),
+		lag:     newLagTracker(),
+		metrics: metrics,
+		rules:   rules,
 		service: services.Order,
-	}
+
+		retryLogEvery: cfg.RetryLogEvery,
+		batchSize:     batchSize,
+		batchTimeout:  cfg.BatchTimeout,
+	}, nil
 }
 
-// Listen starts the message consumption loop.
+// Listen starts the message consumption loop. It returns once ctx is canceled
+// and every worker has finished its batch, so the reader and the DLQ may be closed then.
 func (k *KafkaConsumer) Listen(ctx context.Context) error {
 	const op = "broker.KafkaConsumer.Listen"
 
-	k.log.Info("Kafka reader is running")
+	k.log.Info("Kafka reader is running",
+		zap.Int("workers", k.workers),
+	)
+
+	queues := make([]chan delivery, k.workers)
+
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan delivery, 1)
+
+		wg.Add(1)
+		go func(queue <-chan delivery) {
+			defer wg.Done()
+			k.work(ctx, queue)
+		}(queues[i])
+	}
+
+	defer func() {
+		for _, queue := range queues {
+			close(queue)
+		}
+		wg.Wait()
+	}()
 
 	for {
-		msg, err := k.reader.ReadMessage(ctx)
+		msg, err := k.reader.FetchMessage(ctx)
 		if err != nil {
-			if errors.Is(err, context.Canceled) {
+			if errors.Is(err, context.Canceled) || ctx.Err() != nil {
 				k.log.Info("Consumer context canceled")
 
 				return nil
 			}
 
+			k.log.Error("Failed to fetch message from Kafka",
+				zap.String("op", op),
+				zap.Error(err),
+			)
+
 			continue
 		}
 
-		k.log.Info("Message received from Kafka",
-			zap.String("Topic", msg.Topic),
-			zap.Int("Partition", msg.Partition),
-			zap.Int64("Offset", msg.Offset),
-			zap.String("Timestamp", msg.Time.Format(time.RFC3339)),
-		)
+		k.offsets.track(msg)
+		lag := msg.HighWaterMark - msg.Offset - 1
+		k.lag.set(msg.Partition, lag)
+		k.metrics.Lag(msg.Partition, lag)
 
-		var order Order
+		d := startProcessing(ctx, msg)
 
-		err = json.Unmarshal(msg.Value, &order)
-		if err != nil {
-			k.log.Error("Failed to unmarshal data",
-				zap.String("op", op),
-				zap.Error(err),
-			)
+		select {
+		case queues[msg.Partition%k.workers] <- d:
+		case <-ctx.Done():
+			d.end()
+			k.log.Info("Consumer context canceled")
+
+			return nil
+		}
+	}
+}
+
+// work collects messages of the partitions assigned to one worker into batches
+// of up to batchSize messages or whatever arrived within batchTimeout.
+func (k *KafkaConsumer) work(ctx context.Context, queue <-chan delivery) {
+	batch := make([]delivery, 0, k.batchSize)
+
+	for d := range queue {
+		batch = append(batch[:0], d)
 
+		timer := time.NewTimer(k.batchTimeout)
+	collect:
+		for len(batch) < k.batchSize {
+			select {
+			case next, ok := <-queue:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, next)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		if ctx.Err() != nil {
+			for _, d := range batch {
+				d.end()
+			}
 			continue
 		}
 
-		if err := validator.New().Struct(order); err != nil {
-			validateErr := err.(validator.ValidationErrors)
+		k.handleBatch(ctx, batch)
+	}
+}
 
-			k.log.Error("Invalid data",
-				zap.String("op", op),
-				zap.Error(validateErr),
-			)
+// pendingOrder is a decoded and validated message waiting to be saved.
+type pendingOrder struct {
+	ctx   context.Context
+	msg   kafka.Message
+	order Order
+}
+
+// handleBatch decodes the messages, saves the valid orders with a single batch
+// and commits every message that is done with at once.
+func (k *KafkaConsumer) handleBatch(ctx context.Context, batch []delivery) {
+	const op = "broker.KafkaConsumer.handleBatch"
+
+	defer func() {
+		for _, d := range batch {
+			d.end()
+		}
+	}()
+
+	done := make([]kafka.Message, 0, len(batch))
+	pending := make([]pendingOrder, 0, len(batch))
+
+	for _, d := range batch {
+		msg := d.msg
+
+		if eventType(msg) == EventOrderUpdated {
+			// Orders created earlier in the batch must be stored before they are updated.
+			if len(pending) > 0 {
+				done = append(done, k.saveBatch(ctx, pending)...)
+				pending = pending[:0]
+			}
+
+			if k.handleUpdate(d.ctx, msg) {
+				done = append(done, msg)
+			}
 
 			continue
 		}
 
-		err = k.service.SaveOrder(ctx, order.OrderUID, msg.Value)
-		if err != nil {
-			k.log.Error("Unexpected error",
-				zap.String("op", op),
-				zap.Error(err),
+		order, ok, handled := k.decode(d.ctx, msg)
+		if !ok {
+			if handled {
+				done = append(done, msg)
+			}
+			continue
+		}
+		pending = append(pending, pendingOrder{ctx: d.ctx, msg: msg, order: order})
+	}
+
+	if len(pending) > 0 {
+		done = append(done, k.saveBatch(ctx, pending)...)
+	}
+
+	if len(done) > 0 {
+		k.ack(ctx, done...)
+	}
+
+	k.log.Info("Batch processed",
+		zap.String("op", op),
+		zap.Int("received", len(batch)),
+		zap.Int("done", len(done)),
+	)
+}
+
+// decode unmarshals and validates a message.
+// ok reports whether the order is valid; for an invalid message handled reports
+// whether it was forwarded to the DLQ and may be committed.
+func (k *KafkaConsumer) decode(ctx context.Context, msg kafka.Message) (Order, bool, bool) {
+	const op = "broker.KafkaConsumer.decode"
+
+	k.log.Info("Message received from Kafka",
+		zap.String("Topic", msg.Topic),
+		zap.Int("Partition", msg.Partition),
+		zap.Int64("Offset", msg.Offset),
+		zap.String("Timestamp", msg.Time.Format(time.RFC3339)),
+	)
+
+	if err := schema.ValidateOrder(msg.Value); err != nil {
+		k.log.Error("Invalid data",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+
+		return Order{}, false, k.reject(ctx, msg, rejectReason(err), op, err)
+	}
+
+	var order Order
+
+	err := json.Unmarshal(msg.Value, &order)
+	if err != nil {
+		k.log.Error("Failed to unmarshal data",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+
+		return Order{}, false, k.reject(ctx, msg, ReasonUnmarshal, op, err)
+	}
+
+	if err := k.rules.Check(order); err != nil {
+		k.log.Error("Order breaks business rules",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+
+		return Order{}, false, k.reject(ctx, msg, rejectReason(err), op, err)
+	}
+
+	return order, true, true
+}
+
+// saveBatch saves the orders with a single batch and returns the messages that are done with.
+// If the batch as a whole is rejected, orders are saved one by one, so a single bad order
+// doesn't hold back the others. If it fails with a transient error, e.g. the database is down,
+// saving orders one by one would fail the same way, so the whole batch is left uncommitted.
+func (k *KafkaConsumer) saveBatch(ctx context.Context, pending []pendingOrder) []kafka.Message {
+	const op = "broker.KafkaConsumer.saveBatch"
+
+	orders := make([]entity.Order, len(pending))
+	links := make([]trace.Link, len(pending))
+	for i, p := range pending {
+		orders[i] = entity.Order{UID: p.order.OrderUID, Data: p.msg.Value}
+		links[i] = trace.LinkFromContext(p.ctx)
+	}
+
+	// The batch span is linked to the spans of its messages.
+	ctx, span := tracer.Start(ctx, op,
+		trace.WithLinks(links...),
+		trace.WithAttributes(semconv.MessagingBatchMessageCount(len(pending))),
+	)
+	defer span.End()
+
+	var results []error
+
+	err := k.retryTransient(ctx, func(ctx context.Context) error {
+		var err error
+		results, err = k.service.SaveOrders(ctx, orders)
+		return err
+	})
+	if err != nil && ctx.Err() != nil {
+		k.log.Info("Consumer context canceled, batch left uncommitted",
+			zap.Int("count", len(pending)),
+		)
+
+		return nil
+	}
+
+	if err != nil && IsTransient(err) {
+		k.log.Error("Failed to save batch, batch left uncommitted",
+			zap.String("op", op),
+			zap.Int("count", len(pending)),
+			zap.Error(err),
+		)
+		tracing.Fail(span, err)
+
+		return nil
+	}
+
+	if err != nil {
+		k.log.Error("Failed to save batch, saving orders one by one",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+		tracing.Fail(span, err)
+	}
+
+	done := make([]kafka.Message, 0, len(pending))
+	for i, p := range pending {
+		switch {
+		case err == nil && results[i] == nil:
+			k.metrics.MessageProcessed(EventOrderCreated)
+			done = append(done, p.msg)
+		case err == nil && !IsTransient(results[i]):
+			if k.rejectSaveErr(p.ctx, p.msg, results[i]) {
+				done = append(done, p.msg)
+			}
+		default:
+			if k.save(p) {
+				done = append(done, p.msg)
+			}
+		}
+	}
+
+	return done
+}
+
+// save saves a single order within the span of its message, retrying transient failures.
+// It returns true if the message is done with and its offset may be committed.
+func (k *KafkaConsumer) save(p pendingOrder) bool {
+	ctx := p.ctx
+
+	err := k.retryTransient(ctx, func(ctx context.Context) error {
+		return k.service.SaveOrder(ctx, p.order.OrderUID, p.msg.Value)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			k.log.Info("Consumer context canceled, message left uncommitted",
+				zap.Int("Partition", p.msg.Partition),
+				zap.Int64("Offset", p.msg.Offset),
 			)
 
-			continue
+			return false
+		}
+
+		return k.rejectSaveErr(ctx, p.msg, err)
+	}
+
+	k.metrics.MessageProcessed(EventOrderCreated)
+
+	return true
+}
+
+// rejectSaveErr forwards a message that could not be saved because of a permanent error to the DLQ.
+func (k *KafkaConsumer) rejectSaveErr(ctx context.Context, msg kafka.Message, err error) bool {
+	const op = "broker.KafkaConsumer.save"
+
+	switch {
+	case errors.Is(err, service.ErrOrderConflict):
+		return k.reject(ctx, msg, ReasonConflict, op, err)
+	case errors.Is(err, service.ErrOrderNotFound):
+		return k.reject(ctx, msg, ReasonNotFound, op, err)
+	case errors.Is(err, service.ErrInvalidTransition):
+		return k.reject(ctx, msg, ReasonTransition, op, err)
+	}
+
+	k.log.Error("Unexpected error",
+		zap.String("op", op),
+		zap.Error(err),
+	)
+
+	return k.reject(ctx, msg, ReasonSave, op, err)
+}
+
+// ack marks messages as processed and commits, with a single request, the highest offset
+// of every partition below which all fetched messages have been processed.
+func (k *KafkaConsumer) ack(ctx context.Context, msgs ...kafka.Message) {
+	const op = "broker.KafkaConsumer.ack"
+
+	latest := make(map[partitionKey]kafka.Message)
+	for _, msg := range msgs {
+		if commit, ok := k.offsets.done(msg); ok {
+			latest[partitionKey{topic: commit.Topic, partition: commit.Partition}] = commit
+		}
+	}
+
+	if len(latest) == 0 {
+		return
+	}
+
+	commits := make([]kafka.Message, 0, len(latest))
+	for _, commit := range latest {
+		commits = append(commits, commit)
+	}
+
+	if err := k.reader.CommitMessages(ctx, commits...); err != nil {
+		k.log.Error("Failed to commit message to Kafka",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+	}
+}
+
+// reject forwards a message to the DLQ, retrying with the backoff of the retry policy until
+// it succeeds, so that a rejected message never holds back the commits of its partition.
+// A message the DLQ can never accept, e.g. because of its size, is dropped after logging it.
+// It returns false only if ctx is canceled first; the message then stays uncommitted.
+func (k *KafkaConsumer) reject(ctx context.Context, msg kafka.Message, reason, failedOp string, cause error) bool {
+	const op = "broker.KafkaConsumer.reject"
+
+	k.metrics.MessageFailed(reason)
+
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("dlq.reason", reason))
+	tracing.Fail(span, cause)
+
+	letter := DeadLetter{
+		Message: msg,
+		Reason:  reason,
+		Op:      failedOp,
+		Err:     cause,
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := k.dlq.Publish(ctx, letter)
+		if err == nil {
+			return true
 		}
 
-		if err := k.reader.CommitMessages(ctx, msg); err != nil {
-			k.log.Error("Failed to commit message to Kafka",
+		if isPermanentPublishError(err) {
+			k.log.Error("DLQ can't accept message, dropping it",
 				zap.String("op", op),
+				zap.String("reason", reason),
+				zap.Int("Partition", msg.Partition),
+				zap.Int64("Offset", msg.Offset),
+				zap.ByteString("Key", msg.Key),
 				zap.Error(err),
 			)
+
+			return true
+		}
+
+		k.log.Error("Failed to forward message to DLQ, retrying",
+			zap.String("op", op),
+			zap.String("reason", reason),
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(k.retry.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			k.log.Info("Consumer context canceled, message left uncommitted",
+				zap.Int("Partition", msg.Partition),
+				zap.Int64("Offset", msg.Offset),
+			)
+
+			return false
+		case <-timer.C:
 		}
 	}
 }
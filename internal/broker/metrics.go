@@ -0,0 +1,18 @@
+package broker
+
+// Metrics receives the consumer events worth monitoring.
+type Metrics interface {
+	// MessageProcessed is called when a message of the given event type is applied.
+	MessageProcessed(event string)
+	// MessageFailed is called when a message is rejected for the given reason.
+	MessageFailed(reason string)
+	// Lag is called with the number of messages behind the end of a partition.
+	Lag(partition int, lag int64)
+}
+
+// noopMetrics is used when KafkaConsumerConfig.Metrics is not set.
+type noopMetrics struct{}
+
+func (noopMetrics) MessageProcessed(string) {}
+func (noopMetrics) MessageFailed(string)    {}
+func (noopMetrics) Lag(int, int64)          {}
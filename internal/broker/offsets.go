@@ -0,0 +1,87 @@
+package broker
+
+import (
+	"github.com/segmentio/kafka-go"
+	"sort"
+	"sync"
+)
+
+// offsetTracker tracks in-flight messages per partition and reports the highest
+// offset that can be committed, so messages finishing out of order never commit
+// past a message that is still being processed.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[partitionKey]*partitionOffsets
+}
+
+type partitionKey struct {
+	topic     string
+	partition int
+}
+
+// partitionOffsets holds pending offsets in fetch order and offsets that are done
+// but can't be committed yet because an earlier offset is still pending.
+type partitionOffsets struct {
+	pending []int64
+	done    map[int64]struct{}
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		partitions: make(map[partitionKey]*partitionOffsets),
+	}
+}
+
+// track registers a fetched message as in flight.
+// A message with an offset not greater than the last tracked one means the partition
+// was rewound after a rebalance, so its previous state is discarded.
+func (t *offsetTracker) track(msg kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := partitionKey{topic: msg.Topic, partition: msg.Partition}
+
+	p, ok := t.partitions[key]
+	if !ok || (len(p.pending) > 0 && msg.Offset <= p.pending[len(p.pending)-1]) {
+		p = &partitionOffsets{done: make(map[int64]struct{})}
+		t.partitions[key] = p
+	}
+
+	p.pending = append(p.pending, msg.Offset)
+}
+
+// done marks a message as processed. It returns the message to commit and true when
+// the lowest pending offsets of the partition are all processed.
+func (t *offsetTracker) done(msg kafka.Message) (kafka.Message, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := partitionKey{topic: msg.Topic, partition: msg.Partition}
+
+	p, ok := t.partitions[key]
+	if !ok {
+		return kafka.Message{}, false
+	}
+
+	i := sort.Search(len(p.pending), func(i int) bool { return p.pending[i] >= msg.Offset })
+	if i == len(p.pending) || p.pending[i] != msg.Offset {
+		return kafka.Message{}, false
+	}
+	p.done[msg.Offset] = struct{}{}
+
+	committable := int64(-1)
+	for len(p.pending) > 0 {
+		if _, ok := p.done[p.pending[0]]; !ok {
+			break
+		}
+		committable = p.pending[0]
+		delete(p.done, committable)
+		p.pending = p.pending[1:]
+	}
+
+	if committable < 0 {
+		return kafka.Message{}, false
+	}
+
+	return kafka.Message{Topic: msg.Topic, Partition: msg.Partition, Offset: committable}, true
+}
@@ -0,0 +1,67 @@
+package broker
+
+import (
+	"github.com/segmentio/kafka-go"
+	"testing"
+)
+
+func TestOffsetTrackerOutOfOrder(t *testing.T) {
+	const topic = "orders"
+
+	msg := func(partition int, offset int64) kafka.Message {
+		return kafka.Message{Topic: topic, Partition: partition, Offset: offset}
+	}
+
+	tracker := newOffsetTracker()
+	for offset := int64(0); offset < 5; offset++ {
+		tracker.track(msg(0, offset))
+	}
+	for offset := int64(10); offset < 13; offset++ {
+		tracker.track(msg(1, offset))
+	}
+
+	// commit is the offset expected to be committed after the message is done, or -1 for none.
+	steps := []struct {
+		partition int
+		offset    int64
+		commit    int64
+	}{
+		{0, 2, -1},
+		{0, 1, -1},
+		{1, 11, -1},
+		{0, 0, 2},
+		{1, 10, 11},
+		{0, 4, -1},
+		{0, 3, 4},
+		{1, 12, 12},
+	}
+
+	committed := map[int]int64{0: -1, 1: -1}
+
+	for _, step := range steps {
+		commit, ok := tracker.done(msg(step.partition, step.offset))
+
+		if step.commit < 0 {
+			if ok {
+				t.Fatalf("done(%d/%d) committed offset %d past a pending message", step.partition, step.offset, commit.Offset)
+			}
+			continue
+		}
+
+		if !ok {
+			t.Fatalf("done(%d/%d) didn't commit; want offset %d", step.partition, step.offset, step.commit)
+		}
+		if commit.Topic != topic || commit.Partition != step.partition || commit.Offset != step.commit {
+			t.Fatalf("done(%d/%d) committed %s/%d/%d; want %s/%d/%d", step.partition, step.offset,
+				commit.Topic, commit.Partition, commit.Offset, topic, step.partition, step.commit)
+		}
+		if commit.Offset <= committed[step.partition] {
+			t.Fatalf("partition %d commit moved back from %d to %d", step.partition, committed[step.partition], commit.Offset)
+		}
+		committed[step.partition] = commit.Offset
+	}
+
+	if committed[0] != 4 || committed[1] != 12 {
+		t.Fatalf("committed offsets = %v; want every message committed", committed)
+	}
+}
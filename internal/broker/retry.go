@@ -0,0 +1,157 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+	"wb-internship-l0/internal/service"
+)
+
+// RetryPolicy describes the backoff between attempts to retry transient failures.
+type RetryPolicy struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Jitter          float64
+}
+
+// Validate reports an error if the policy would retry without waiting or with a shrinking delay.
+func (p RetryPolicy) Validate() error {
+	const op = "broker.RetryPolicy.Validate"
+
+	switch {
+	case p.InitialInterval <= 0:
+		return fmt.Errorf("%s: initial interval must be positive, got %s", op, p.InitialInterval)
+	case p.MaxInterval < 0:
+		return fmt.Errorf("%s: max interval must not be negative, got %s", op, p.MaxInterval)
+	case p.Multiplier < 1:
+		return fmt.Errorf("%s: multiplier must be at least 1, got %g", op, p.Multiplier)
+	case p.Jitter < 0 || p.Jitter >= 1:
+		return fmt.Errorf("%s: jitter must be in [0, 1), got %g", op, p.Jitter)
+	default:
+		return nil
+	}
+}
+
+// Backoff returns the delay before the given retry attempt, starting from 1.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := float64(p.InitialInterval)
+	for i := 1; i < attempt; i++ {
+		delay *= p.Multiplier
+		if p.MaxInterval > 0 && delay >= float64(p.MaxInterval) {
+			delay = float64(p.MaxInterval)
+			break
+		}
+	}
+
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1) //nolint:gosec // jitter does not need a secure source
+	}
+
+	return time.Duration(delay)
+}
+
+// Retry calls fn until it succeeds or fails with a permanent error, waiting between attempts
+// as the policy describes. It stops waiting as soon as ctx is canceled and returns the last error.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil || !IsTransient(err) {
+			return err
+		}
+
+		timer := time.NewTimer(policy.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// retryTransient calls fn until it succeeds, fails with a permanent error or ctx is canceled.
+// Transient errors are never given up on, so a database outage stalls the consumer instead of
+// dead-lettering the messages; an error is logged after every retryLogEvery failed attempts.
+func (k *KafkaConsumer) retryTransient(ctx context.Context, fn func(ctx context.Context) error) error {
+	const op = "broker.KafkaConsumer.retryTransient"
+
+	attempts := 0
+
+	return Retry(ctx, k.retry, func(ctx context.Context) error {
+		err := fn(ctx)
+		attempts++
+
+		if k.retryLogEvery > 0 && attempts%k.retryLogEvery == 0 && IsTransient(err) {
+			k.log.Error("Transient failure persists, still retrying",
+				zap.String("op", op),
+				zap.Int("attempts", attempts),
+				zap.Error(err),
+			)
+		}
+
+		return err
+	})
+}
+
+// IsTransient reports whether err is caused by a temporary condition such as
+// a lost connection or a timeout, and the operation may succeed when retried.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, service.ErrOrderAlreadyExists) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	// The order changed under a concurrent update: trying again re-reads it.
+	if errors.Is(err, service.ErrConcurrentUpdate) {
+		return true
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err) || pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return isTransientPgCode(pgErr.Code)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// isTransientPgCode reports whether a SQLSTATE code describes a temporary failure.
+func isTransientPgCode(code string) bool {
+	switch {
+	case len(code) >= 2 && code[:2] == "08": // connection exception
+		return true
+	case len(code) >= 2 && code[:2] == "53": // insufficient resources
+		return true
+	case code == "40001", code == "40P01": // serialization failure, deadlock
+		return true
+	case code == "57P01", code == "57P02", code == "57P03": // admin shutdown, crash shutdown, cannot connect now
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,173 @@
+package broker
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// Rule is a business invariant of an order that can't be expressed by field validation.
+type Rule struct {
+	// Name identifies the rule in violations.
+	Name string
+	// Reason is the DLQ reason of orders breaking the rule.
+	Reason string
+	// Check returns an error describing the violation, or nil.
+	Check func(order Order) error
+}
+
+// Rules is an ordered set of rules checked against every ingested order.
+type Rules []Rule
+
+// Violation describes an order breaking a rule.
+type Violation struct {
+	Rule   string
+	Reason string
+	Detail string
+}
+
+// Violations is the error returned by Rules.Check. It lists every broken rule.
+type Violations []Violation
+
+func (v Violations) Error() string {
+	msgs := make([]string, len(v))
+	for i, violation := range v {
+		msgs[i] = violation.Rule + ": " + violation.Detail
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Reason returns the rejection reason of the first broken rule.
+func (v Violations) Reason() string {
+	return v[0].Reason
+}
+
+// Rules returns the names of the broken rules.
+func (v Violations) Rules() []string {
+	names := make([]string, len(v))
+	for i, violation := range v {
+		names[i] = violation.Rule
+	}
+
+	return names
+}
+
+// Check checks the order against every rule.
+// It returns Violations if any rule is broken, so that all problems are reported at once.
+func (r Rules) Check(order Order) error {
+	var violations Violations
+
+	for _, rule := range r {
+		if err := rule.Check(order); err != nil {
+			violations = append(violations, Violation{
+				Rule:   rule.Name,
+				Reason: rule.Reason,
+				Detail: err.Error(),
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return violations
+	}
+
+	return nil
+}
+
+// Rejection reasons of orders breaking the default rules.
+const (
+	ReasonGoodsTotalMismatch  = "goods_total_mismatch"
+	ReasonAmountMismatch      = "amount_mismatch"
+	ReasonTrackNumberMismatch = "track_number_mismatch"
+	ReasonInvalidEmail        = "invalid_email"
+	ReasonInvalidPhone        = "invalid_phone"
+	ReasonInvalidCurrency     = "invalid_currency"
+)
+
+var (
+	// phonePattern matches E.164 numbers.
+	phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	// currencyPattern matches ISO 4217 alphabetic codes.
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// DefaultRules returns the rules applied by the consumer unless configured otherwise.
+// Money rules come first, so an order with wrong totals is rejected for them.
+func DefaultRules() Rules {
+	return Rules{
+		{Name: "goods_total", Reason: ReasonGoodsTotalMismatch, Check: checkGoodsTotal},
+		{Name: "amount", Reason: ReasonAmountMismatch, Check: checkAmount},
+		{Name: "item_track_number", Reason: ReasonTrackNumberMismatch, Check: checkItemTrackNumbers},
+		{Name: "email", Reason: ReasonInvalidEmail, Check: checkEmail},
+		{Name: "phone", Reason: ReasonInvalidPhone, Check: checkPhone},
+		{Name: "currency", Reason: ReasonInvalidCurrency, Check: checkCurrency},
+	}
+}
+
+// checkGoodsTotal checks that goods_total is the sum of item total_price.
+func checkGoodsTotal(order Order) error {
+	sum := 0
+	for _, item := range order.Items {
+		sum += item.TotalPrice
+	}
+
+	if order.Payment.GoodsTotal != sum {
+		return fmt.Errorf("goods_total %d doesn't match the sum of item total_price %d", order.Payment.GoodsTotal, sum)
+	}
+
+	return nil
+}
+
+// checkAmount checks that amount is goods_total plus delivery_cost plus custom_fee.
+func checkAmount(order Order) error {
+	p := order.Payment
+
+	if want := p.GoodsTotal + p.DeliveryCost + p.CustomFee; p.Amount != want {
+		return fmt.Errorf("amount %d doesn't match goods_total + delivery_cost + custom_fee %d", p.Amount, want)
+	}
+
+	return nil
+}
+
+// checkItemTrackNumbers checks that every item has the track number of the order.
+func checkItemTrackNumbers(order Order) error {
+	for i, item := range order.Items {
+		if item.TrackNumber != order.TrackNumber {
+			return fmt.Errorf("items[%d].track_number %q doesn't match the order track_number %q", i, item.TrackNumber, order.TrackNumber)
+		}
+	}
+
+	return nil
+}
+
+// checkEmail checks that the delivery email is a bare address.
+func checkEmail(order Order) error {
+	email := order.Delivery.Email
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return fmt.Errorf("delivery.email %q is not a valid email address", email)
+	}
+
+	return nil
+}
+
+// checkPhone checks that the delivery phone is in E.164 format.
+func checkPhone(order Order) error {
+	if !phonePattern.MatchString(order.Delivery.Phone) {
+		return fmt.Errorf("delivery.phone %q is not an E.164 phone number", order.Delivery.Phone)
+	}
+
+	return nil
+}
+
+// checkCurrency checks that the payment currency is an ISO 4217 code.
+func checkCurrency(order Order) error {
+	if !currencyPattern.MatchString(order.Payment.Currency) {
+		return fmt.Errorf("payment.currency %q is not an ISO 4217 code", order.Payment.Currency)
+	}
+
+	return nil
+}
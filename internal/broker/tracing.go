@@ -0,0 +1,80 @@
+package broker
+
+import (
+	"context"
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"strconv"
+)
+
+var tracer = otel.Tracer("wb-internship-l0/internal/broker")
+
+// delivery is a fetched message together with the context of its processing span.
+type delivery struct {
+	ctx context.Context
+	msg kafka.Message
+}
+
+// startProcessing starts the processing span of a message, continuing the trace
+// of the producer if the message headers carry one.
+func startProcessing(ctx context.Context, msg kafka.Message) delivery {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, &headerCarrier{headers: &msg.Headers})
+
+	ctx, _ = tracer.Start(ctx, "process "+msg.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingDestinationName(msg.Topic),
+			semconv.MessagingDestinationPartitionID(strconv.Itoa(msg.Partition)),
+			semconv.MessagingKafkaMessageOffset(int(msg.Offset)),
+			semconv.MessagingKafkaMessageKey(string(msg.Key)),
+			attribute.String("messaging.event_type", eventType(msg)),
+		),
+	)
+
+	return delivery{ctx: ctx, msg: msg}
+}
+
+// end ends the processing span of the message.
+func (d delivery) end() {
+	trace.SpanFromContext(d.ctx).End()
+}
+
+// headerCarrier adapts Kafka message headers to propagation.TextMapCarrier.
+type headerCarrier struct {
+	headers *[]kafka.Header
+}
+
+func (c *headerCarrier) Get(key string) string {
+	for _, h := range *c.headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+
+	return ""
+}
+
+func (c *headerCarrier) Set(key, value string) {
+	for i, h := range *c.headers {
+		if h.Key == key {
+			(*c.headers)[i].Value = []byte(value)
+			return
+		}
+	}
+
+	*c.headers = append(*c.headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c *headerCarrier) Keys() []string {
+	keys := make([]string, len(*c.headers))
+	for i, h := range *c.headers {
+		keys[i] = h.Key
+	}
+
+	return keys
+}
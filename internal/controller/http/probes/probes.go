@@ -0,0 +1,54 @@
+package probes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"wb-internship-l0/internal/lib/health"
+)
+
+// Paths of the probes, they are excluded from request logging.
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+type probeRoutes struct {
+	log     *zap.Logger
+	checker *health.Checker
+}
+
+// InitRouter registers the liveness and readiness probes.
+func InitRouter(log *zap.Logger, app *fiber.App, readiness *health.Checker) {
+	r := probeRoutes{
+		log:     log,
+		checker: readiness,
+	}
+
+	app.Get(LivenessPath, r.liveness)
+	app.Get(ReadinessPath, r.readiness)
+}
+
+// liveness reports that the process is able to serve requests.
+func (r *probeRoutes) liveness(c *fiber.Ctx) error {
+	return c.JSON(health.Report{
+		Status: health.StatusUp,
+		Checks: map[string]health.Result{},
+	})
+}
+
+// readiness runs the readiness checks and responds with 503 if any of them failed.
+func (r *probeRoutes) readiness(c *fiber.Ctx) error {
+	const op = "probes.probeRoutes.readiness"
+
+	report := r.checker.Run(c.UserContext())
+	if !report.Up() {
+		r.log.Warn("service is not ready",
+			zap.String("op", op),
+			zap.Any("checks", report.Checks),
+		)
+
+		return c.Status(fiber.StatusServiceUnavailable).JSON(report)
+	}
+
+	return c.JSON(report)
+}
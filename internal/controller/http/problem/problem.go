@@ -0,0 +1,143 @@
+// Package problem implements RFC 7807 "problem details" error responses.
+package problem
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.uber.org/zap"
+	"wb-internship-l0/pkg/validation"
+)
+
+// MIMEApplicationProblemJSON is the media type of problem responses.
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Machine-readable error codes. The problem type URI is typeBase followed by the code.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeValidation       = "validation_failed"
+	CodeNotFound         = "not_found"
+	CodeOrderNotFound    = "order_not_found"
+	CodeSchemaNotFound   = "schema_not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeNotAcceptable    = "not_acceptable"
+	CodeInternal         = "internal_error"
+)
+
+const typeBase = "/problems/"
+
+// Problem is the body of an error response.
+type Problem struct {
+	Type      string                  `json:"type"`
+	Title     string                  `json:"title"`
+	Status    int                     `json:"status"`
+	Detail    string                  `json:"detail,omitempty"`
+	Instance  string                  `json:"instance,omitempty"`
+	Code      string                  `json:"code"`
+	RequestID string                  `json:"request_id,omitempty"`
+	Errors    []validation.FieldError `json:"errors,omitempty"`
+}
+
+// Error is returned by handlers to respond with a problem.
+type Error struct {
+	Status int
+	Code   string
+	Detail string
+	Fields []validation.FieldError
+}
+
+// New returns an Error with the given status, code and human-readable detail.
+func New(status int, code, detail string) *Error {
+	return &Error{
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+// Validation returns a 400 Error listing the invalid fields.
+func Validation(errs validator.ValidationErrors) *Error {
+	return &Error{
+		Status: fiber.StatusBadRequest,
+		Code:   CodeValidation,
+		Detail: "request is not valid",
+		Fields: validation.Fields(errs),
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Detail
+}
+
+// Unwrap exposes the status as a *fiber.Error, so that middleware can tell the response status.
+func (e *Error) Unwrap() error {
+	return fiber.NewError(e.Status, e.Detail)
+}
+
+// Handler is the Fiber error handler responding with problems to every error returned by handlers.
+// Unexpected errors are logged and reported without details.
+func Handler(log *zap.Logger) fiber.ErrorHandler {
+	const op = "problem.Handler"
+
+	return func(c *fiber.Ctx, err error) error {
+		var (
+			apiErr   *Error
+			fiberErr *fiber.Error
+		)
+
+		switch {
+		case errors.As(err, &apiErr):
+		case errors.As(err, &fiberErr):
+			apiErr = New(fiberErr.Code, codeForStatus(fiberErr.Code), fiberErr.Message)
+		default:
+			log.Error("unhandled error",
+				zap.String("op", op),
+				zap.String("route", c.Route().Path),
+				zap.String("requestId", c.GetRespHeader(fiber.HeaderXRequestID)),
+				zap.Error(err),
+			)
+
+			apiErr = New(fiber.StatusInternalServerError, CodeInternal, "")
+		}
+
+		return Send(c, apiErr)
+	}
+}
+
+// Send responds with the problem described by the error.
+func Send(c *fiber.Ctx, e *Error) error {
+	requestID, _ := c.Locals("requestid").(string)
+
+	body := Problem{
+		Type:      typeBase + e.Code,
+		Title:     utils.StatusMessage(e.Status),
+		Status:    e.Status,
+		Detail:    e.Detail,
+		Instance:  c.OriginalURL(),
+		Code:      e.Code,
+		RequestID: requestID,
+		Errors:    e.Fields,
+	}
+
+	return c.Status(e.Status).JSON(body, MIMEApplicationProblemJSON)
+}
+
+func codeForStatus(status int) string {
+	switch status {
+	case fiber.StatusNotFound:
+		return CodeNotFound
+	case fiber.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case fiber.StatusNotAcceptable:
+		return CodeNotAcceptable
+	case fiber.StatusInternalServerError:
+		return CodeInternal
+	}
+
+	if status >= fiber.StatusBadRequest && status < fiber.StatusInternalServerError {
+		return CodeBadRequest
+	}
+
+	return CodeInternal
+}
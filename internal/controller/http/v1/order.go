@@ -1,11 +1,18 @@
 package v1
 
 import (
-	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/etag"
 	"go.uber.org/zap"
+	"net/url"
+	"time"
+	"wb-internship-l0/internal/controller/http/problem"
+	"wb-internship-l0/internal/entity"
 	"wb-internship-l0/internal/service"
 	"wb-internship-l0/pkg/validation"
 )
@@ -22,8 +29,14 @@ func newRoutes(log *zap.Logger, g *fiber.Router, orderService service.Order) {
 	}
 
 	(*g).Get("/get_order", r.getOrder)
+	(*g).Get("/orders", r.listOrders)
+	(*g).Get("/orders/:order_uid", etag.New(), r.getOrderByUID)
+	(*g).Get("/orders/:order_uid/history", r.getOrderHistory)
 }
 
+// maxOrderUIDLength is the length of the OrderID column.
+const maxOrderUIDLength = 255
+
 type Request struct {
 	ID string `json:"order_uid" validate:"required"`
 }
@@ -40,44 +53,323 @@ func (r *orderRoutes) getOrder(c *fiber.Ctx) error {
 			zap.Error(err),
 		)
 
-		return c.SendStatus(fiber.StatusBadRequest)
+		return problem.New(fiber.StatusBadRequest, problem.CodeBadRequest, "request body is not valid JSON")
 	}
 
 	r.log.Info("request body decoded")
-	if err := validator.New().Struct(req); err != nil {
+	if err := validation.New().Struct(req); err != nil {
 		validateErr := err.(validator.ValidationErrors)
 		r.log.Error("invalid request",
 			zap.String("op", op),
 			zap.Error(err),
 		)
 
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"errors": validation.ValidataionError(validateErr),
-		})
+		return problem.Validation(validateErr)
+	}
+
+	return r.sendOrder(c, op, "api/v1/get_order", req.ID)
+}
+
+// getOrderByUID is the RESTful counterpart of getOrder that takes the order UID from the path.
+func (r *orderRoutes) getOrderByUID(c *fiber.Ctx) error {
+	const op = "v1.orderRoutes.getOrderByUID"
+
+	c.Vary(fiber.HeaderAccept)
+
+	if c.Accepts(fiber.MIMEApplicationJSON) == "" {
+		return problem.New(fiber.StatusNotAcceptable, problem.CodeNotAcceptable, "only application/json is available")
+	}
+
+	id, err := orderUIDParam(c)
+	if err != nil {
+		r.log.Error("invalid request",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+
+		return err
 	}
 
-	data, err := r.orderService.GetOrder(context.Background(), req.ID)
+	return r.sendOrder(c, op, "api/v1/orders/:order_uid", id)
+}
+
+// sendOrder responds with the current order, or with its past state if as_of is set.
+func (r *orderRoutes) sendOrder(c *fiber.Ctx, op, route, id string) error {
+	asOf, err := parseAsOf(c)
+	if err != nil {
+		r.log.Error("invalid request",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+
+		return invalidParam("as_of", "rfc3339", "as_of must be an RFC 3339 timestamp")
+	}
+
+	var data json.RawMessage
+	if asOf.IsZero() {
+		data, err = r.orderService.GetOrder(c.UserContext(), id)
+	} else {
+		data, err = r.orderService.GetOrderAsOf(c.UserContext(), id, asOf)
+	}
 	if err != nil {
 		if errors.Is(err, service.ErrOrderNotFound) {
 			r.log.Warn("order not found",
 				zap.String("op", op),
-				zap.String("route", "api/v1/get_order"),
-				zap.String("orderID", req.ID),
+				zap.String("route", route),
+				zap.String("orderID", id),
 				zap.Error(err),
 			)
 
-			return c.SendStatus(fiber.StatusNotFound)
+			return orderNotFound(id)
 		}
 
 		r.log.Error("failed to get order",
 			zap.String("op", op),
-			zap.String("route", "api/v1/get_order"),
-			zap.String("orderID", req.ID),
+			zap.String("route", route),
+			zap.String("orderID", id),
 			zap.Error(err),
 		)
 
-		return c.SendStatus(fiber.StatusInternalServerError)
+		return problem.New(fiber.StatusInternalServerError, problem.CodeInternal, "")
 	}
 
 	return c.JSON(data)
 }
+
+type orderVersionResponse struct {
+	Version    int             `json:"version"`
+	Status     string          `json:"status"`
+	RecordedAt time.Time       `json:"recorded_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func (r *orderRoutes) getOrderHistory(c *fiber.Ctx) error {
+	const op = "v1.orderRoutes.getOrderHistory"
+
+	id, err := orderUIDParam(c)
+	if err != nil {
+		r.log.Error("invalid request",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+
+		return err
+	}
+
+	history, err := r.orderService.GetOrderHistory(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrOrderNotFound) {
+			r.log.Warn("order not found",
+				zap.String("op", op),
+				zap.String("route", "api/v1/orders/:order_uid/history"),
+				zap.String("orderID", id),
+				zap.Error(err),
+			)
+
+			return orderNotFound(id)
+		}
+
+		r.log.Error("failed to get order history",
+			zap.String("op", op),
+			zap.String("route", "api/v1/orders/:order_uid/history"),
+			zap.String("orderID", id),
+			zap.Error(err),
+		)
+
+		return problem.New(fiber.StatusInternalServerError, problem.CodeInternal, "")
+	}
+
+	resp := make([]orderVersionResponse, len(history))
+	for i, version := range history {
+		resp[i] = orderVersionResponse{
+			Version:    version.Version,
+			Status:     string(version.Status),
+			RecordedAt: version.RecordedAt,
+			Data:       version.Data,
+		}
+	}
+
+	return c.JSON(resp)
+}
+
+// orderUIDParam returns the order UID from the path. Fiber doesn't unescape path parameters,
+// while clients percent-encode UIDs with reserved or non-ASCII characters.
+func orderUIDParam(c *fiber.Ctx) (string, error) {
+	id, err := url.PathUnescape(c.Params("order_uid"))
+	if err != nil {
+		return "", invalidParam("order_uid", "escape", "order_uid is not correctly percent-encoded")
+	}
+
+	if len(id) > maxOrderUIDLength {
+		return "", invalidParam("order_uid", "max", fmt.Sprintf("order_uid must be at most %d characters", maxOrderUIDLength))
+	}
+
+	return id, nil
+}
+
+// orderNotFound returns the problem reported for an unknown order UID.
+func orderNotFound(id string) error {
+	return problem.New(fiber.StatusNotFound, problem.CodeOrderNotFound, fmt.Sprintf("order %q not found", id))
+}
+
+// invalidParam returns a validation problem for a single malformed parameter.
+func invalidParam(field, code, message string) error {
+	err := problem.New(fiber.StatusBadRequest, problem.CodeValidation, "request is not valid")
+	err.Fields = []validation.FieldError{{Field: field, Code: code, Message: message}}
+
+	return err
+}
+
+// parseAsOf reads the optional as_of query parameter.
+// Returns the zero time if the parameter is not set.
+func parseAsOf(c *fiber.Ctx) (time.Time, error) {
+	raw := c.Query("as_of")
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, raw)
+}
+
+type listRequest struct {
+	CustomerID      string `query:"customer_id"`
+	TrackNumber     string `query:"track_number"`
+	DeliveryService string `query:"delivery_service"`
+	Locale          string `query:"locale"`
+	PaymentProvider string `query:"payment_provider"`
+	CreatedFrom     string `query:"created_from"`
+	CreatedTo       string `query:"created_to"`
+	Cursor          string `query:"cursor"`
+	Limit           int    `query:"limit" validate:"gte=0,lte=100"`
+}
+
+type listResponse struct {
+	Orders     []json.RawMessage `json:"orders"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+type cursorPayload struct {
+	DateCreated time.Time `json:"d"`
+	UID         string    `json:"id"`
+}
+
+func (r *orderRoutes) listOrders(c *fiber.Ctx) error {
+	const op = "v1.orderRoutes.listOrders"
+
+	var req listRequest
+
+	if err := c.QueryParser(&req); err != nil {
+		r.log.Error("failed to decode query",
+			zap.String("op", op),
+			zap.String("route", "api/v1/orders"),
+			zap.Error(err),
+		)
+
+		return problem.New(fiber.StatusBadRequest, problem.CodeBadRequest, "query is not valid")
+	}
+
+	if err := validation.New().Struct(req); err != nil {
+		validateErr := err.(validator.ValidationErrors)
+		r.log.Error("invalid request",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+
+		return problem.Validation(validateErr)
+	}
+
+	filter, err := req.filter()
+	if err != nil {
+		r.log.Error("invalid request",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+
+		return err
+	}
+
+	page, err := r.orderService.ListOrders(c.UserContext(), filter)
+	if err != nil {
+		r.log.Error("failed to list orders",
+			zap.String("op", op),
+			zap.String("route", "api/v1/orders"),
+			zap.Error(err),
+		)
+
+		return problem.New(fiber.StatusInternalServerError, problem.CodeInternal, "")
+	}
+
+	resp := listResponse{
+		Orders: make([]json.RawMessage, len(page.Orders)),
+	}
+	for i, order := range page.Orders {
+		resp.Orders[i] = order.Data
+	}
+	if page.Next != nil {
+		resp.NextCursor = encodeCursor(*page.Next)
+	}
+
+	return c.JSON(resp)
+}
+
+// filter converts the query parameters to an entity.OrderFilter.
+func (req listRequest) filter() (entity.OrderFilter, error) {
+	filter := entity.OrderFilter{
+		CustomerID:      req.CustomerID,
+		TrackNumber:     req.TrackNumber,
+		DeliveryService: req.DeliveryService,
+		Locale:          req.Locale,
+		PaymentProvider: req.PaymentProvider,
+		Limit:           req.Limit,
+	}
+
+	var err error
+
+	if req.CreatedFrom != "" {
+		if filter.CreatedFrom, err = time.Parse(time.RFC3339, req.CreatedFrom); err != nil {
+			return entity.OrderFilter{}, invalidParam("created_from", "rfc3339", "created_from must be an RFC 3339 timestamp")
+		}
+	}
+	if req.CreatedTo != "" {
+		if filter.CreatedTo, err = time.Parse(time.RFC3339, req.CreatedTo); err != nil {
+			return entity.OrderFilter{}, invalidParam("created_to", "rfc3339", "created_to must be an RFC 3339 timestamp")
+		}
+	}
+	if req.Cursor != "" {
+		cursor, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return entity.OrderFilter{}, invalidParam("cursor", "cursor", "cursor is not valid")
+		}
+		filter.After = &cursor
+	}
+
+	return filter, nil
+}
+
+// encodeCursor returns an opaque representation of the cursor for clients.
+func encodeCursor(cursor entity.OrderCursor) string {
+	payload, _ := json.Marshal(cursorPayload{
+		DateCreated: cursor.DateCreated,
+		UID:         cursor.UID,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// decodeCursor parses a cursor returned by encodeCursor.
+func decodeCursor(s string) (entity.OrderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return entity.OrderCursor{}, err
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return entity.OrderCursor{}, err
+	}
+
+	return entity.OrderCursor{
+		DateCreated: payload.DateCreated,
+		UID:         payload.UID,
+	}, nil
+}
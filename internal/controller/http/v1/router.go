@@ -10,4 +10,5 @@ func InitRouter(log *zap.Logger, app *fiber.App, services *service.Services) {
 	v1 := app.Group("api/v1")
 
 	newRoutes(log, &v1, services.Order)
+	newSchemaRoutes(&v1)
 }
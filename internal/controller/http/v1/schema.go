@@ -0,0 +1,32 @@
+package v1
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/etag"
+	"wb-internship-l0/internal/controller/http/problem"
+	"wb-internship-l0/internal/schema"
+)
+
+// MIMEApplicationSchemaJSON is the media type of JSON Schema documents.
+const MIMEApplicationSchemaJSON = "application/schema+json"
+
+// newSchemaRoutes serves the schemas of messages, so producers can validate them before publishing.
+func newSchemaRoutes(g *fiber.Router) {
+	(*g).Get("/schemas/order", etag.New(), getOrderSchema)
+	(*g).Get("/schemas/order/:version", etag.New(), getOrderSchema)
+}
+
+// getOrderSchema responds with the requested version of the order schema, or with the current one.
+func getOrderSchema(c *fiber.Ctx) error {
+	version := c.Params("version", schema.OrderVersion)
+
+	doc, ok := schema.Order(version)
+	if !ok {
+		return problem.New(fiber.StatusNotFound, problem.CodeSchemaNotFound, fmt.Sprintf("order schema %q not found", version))
+	}
+
+	c.Set(fiber.HeaderContentType, MIMEApplicationSchemaJSON)
+
+	return c.Send(doc)
+}
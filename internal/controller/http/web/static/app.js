@@ -0,0 +1,159 @@
+"use strict";
+
+const form = document.getElementById("lookup");
+const input = document.getElementById("uid");
+const button = form.querySelector("button");
+const message = document.getElementById("message");
+const orderSection = document.getElementById("order");
+
+const orderFields = [
+    ["order_uid", "UID"],
+    ["track_number", "Трек-номер"],
+    ["entry", "Entry"],
+    ["locale", "Локаль"],
+    ["internal_signature", "Внутренняя подпись"],
+    ["customer_id", "ID покупателя"],
+    ["delivery_service", "Служба доставки"],
+    ["shardkey", "Shard key"],
+    ["sm_id", "SM ID"],
+    ["date_created", "Дата создания", formatDate],
+    ["oof_shard", "OOF shard"],
+];
+
+const deliveryFields = [
+    ["name", "Получатель"],
+    ["phone", "Телефон"],
+    ["zip", "Индекс"],
+    ["city", "Город"],
+    ["address", "Адрес"],
+    ["region", "Регион"],
+    ["email", "Email"],
+];
+
+const paymentFields = [
+    ["transaction", "Транзакция"],
+    ["request_id", "ID запроса"],
+    ["currency", "Валюта"],
+    ["provider", "Провайдер"],
+    ["amount", "Сумма"],
+    ["payment_dt", "Дата оплаты", formatUnix],
+    ["bank", "Банк"],
+    ["delivery_cost", "Стоимость доставки"],
+    ["goods_total", "Стоимость товаров"],
+    ["custom_fee", "Пошлина"],
+];
+
+const itemFields = [
+    ["chrt_id", "chrt_id"],
+    ["nm_id", "nm_id"],
+    ["name", "Название"],
+    ["brand", "Бренд"],
+    ["size", "Размер"],
+    ["price", "Цена"],
+    ["sale", "Скидка, %"],
+    ["total_price", "Итого"],
+    ["track_number", "Трек-номер"],
+    ["rid", "rid"],
+    ["status", "Статус"],
+];
+
+function formatDate(value) {
+    const date = new Date(value);
+    return isNaN(date) ? value : date.toLocaleString();
+}
+
+function formatUnix(value) {
+    return typeof value === "number" ? new Date(value * 1000).toLocaleString() : value;
+}
+
+function display(value, format) {
+    if (value === undefined || value === null || value === "") {
+        return "—";
+    }
+    return String(format ? format(value) : value);
+}
+
+// Values are always set through textContent, so order data can't inject markup.
+function renderVertical(table, data, fields) {
+    table.replaceChildren();
+    for (const [key, title, format] of fields) {
+        const row = table.insertRow();
+        const th = document.createElement("th");
+        th.textContent = title;
+        row.appendChild(th);
+        row.insertCell().textContent = display((data || {})[key], format);
+    }
+}
+
+function renderGrid(table, rows, fields) {
+    table.replaceChildren();
+    const head = table.createTHead().insertRow();
+    for (const [, title] of fields) {
+        const th = document.createElement("th");
+        th.textContent = title;
+        head.appendChild(th);
+    }
+    const body = table.createTBody();
+    for (const item of rows || []) {
+        const row = body.insertRow();
+        for (const [key, , format] of fields) {
+            row.insertCell().textContent = display(item[key], format);
+        }
+    }
+}
+
+function showMessage(text, kind) {
+    message.textContent = text;
+    message.className = "message " + kind;
+    message.hidden = false;
+    orderSection.hidden = true;
+}
+
+function render(order) {
+    message.hidden = true;
+    document.getElementById("order-uid").textContent = order.order_uid;
+    document.getElementById("order-status").textContent = order.status || "";
+    renderVertical(document.getElementById("order-table"), order, orderFields);
+    renderVertical(document.getElementById("delivery-table"), order.delivery, deliveryFields);
+    renderVertical(document.getElementById("payment-table"), order.payment, paymentFields);
+    renderGrid(document.getElementById("items-table"), order.items, itemFields);
+    orderSection.hidden = false;
+}
+
+async function lookup(uid) {
+    button.disabled = true;
+    try {
+        const response = await fetch("/api/v1/orders/" + encodeURIComponent(uid), {
+            headers: {Accept: "application/json"},
+        });
+        if (response.status === 404) {
+            showMessage("Заказ «" + uid + "» не найден.", "not-found");
+            return;
+        }
+        if (!response.ok) {
+            showMessage("Не удалось получить заказ: сервер ответил " + response.status + ".", "error");
+            return;
+        }
+        render(await response.json());
+    } catch (err) {
+        showMessage("Не удалось связаться с сервером: " + err.message, "error");
+    } finally {
+        button.disabled = false;
+    }
+}
+
+form.addEventListener("submit", (event) => {
+    event.preventDefault();
+    const uid = input.value.trim();
+    if (!uid) {
+        return;
+    }
+    history.replaceState(null, "", "?uid=" + encodeURIComponent(uid));
+    lookup(uid);
+});
+
+const initial = new URLSearchParams(location.search).get("uid");
+if (initial) {
+    input.value = initial;
+    lookup(initial);
+}
@@ -0,0 +1,26 @@
+package web
+
+import (
+	"embed"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/filesystem"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var static embed.FS
+
+// InitRouter serves the embedded web interface for looking up orders at the root path.
+func InitRouter(app *fiber.App) {
+	root, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+
+	app.Use("/", filesystem.New(filesystem.Config{
+		Root:   http.FS(root),
+		Index:  "index.html",
+		Browse: false,
+	}))
+}
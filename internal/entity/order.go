@@ -1,8 +1,61 @@
 package entity
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
+
+// OrderStatus is a stage of the order lifecycle.
+type OrderStatus string
+
+const (
+	StatusCreated    OrderStatus = "created"
+	StatusAssembling OrderStatus = "assembling"
+	StatusShipped    OrderStatus = "shipped"
+	StatusInTransit  OrderStatus = "in_transit"
+	StatusDelivered  OrderStatus = "delivered"
+	StatusCanceled   OrderStatus = "canceled"
+	StatusReturned   OrderStatus = "returned"
+)
 
 type Order struct {
-	UID  string
-	Data json.RawMessage
+	UID     string
+	Version int
+	Status  OrderStatus
+	Data    json.RawMessage
+}
+
+// OrderVersion is a past or current state of an order recorded in its history.
+type OrderVersion struct {
+	Order
+	RecordedAt time.Time
+}
+
+// OrderFilter holds the conditions and position of an order listing.
+// Empty fields don't restrict the listing.
+type OrderFilter struct {
+	CustomerID      string
+	TrackNumber     string
+	DeliveryService string
+	Locale          string
+	PaymentProvider string
+	CreatedFrom     time.Time
+	CreatedTo       time.Time
+	After           *OrderCursor
+	Limit           int
+}
+
+// OrderCursor points at the last order of a listing page.
+// Orders are listed from the newest to the oldest by creation date, followed by orders
+// without a valid creation date, for which DateCreated is zero.
+type OrderCursor struct {
+	DateCreated time.Time
+	UID         string
+}
+
+// OrderPage is a page of an order listing.
+// Next is nil on the last page.
+type OrderPage struct {
+	Orders []Order
+	Next   *OrderCursor
 }
@@ -0,0 +1,112 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Check statuses.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// CheckFunc reports whether a dependency is usable. It must respect the context deadline.
+type CheckFunc func(ctx context.Context) error
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of all registered checks.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Up reports whether every check passed.
+func (r Report) Up() bool {
+	return r.Status == StatusUp
+}
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Checker runs a set of named checks concurrently.
+type Checker struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks []namedCheck
+}
+
+// NewChecker returns a new instance of Checker giving every check at most timeout to complete.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+	}
+}
+
+// Register adds a check under the given name.
+func (c *Checker) Register(name string, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Run runs every check and reports their status and latency.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+
+	var wg sync.WaitGroup
+	for i, nc := range checks {
+		wg.Add(1)
+		go func(i int, nc namedCheck) {
+			defer wg.Done()
+			results[i] = c.run(ctx, nc.check)
+		}(i, nc)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status: StatusUp,
+		Checks: make(map[string]Result, len(checks)),
+	}
+	for i, nc := range checks {
+		report.Checks[nc.name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check CheckFunc) Result {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := check(ctx)
+	latency := float64(time.Since(start).Microseconds()) / 1000
+
+	if err != nil {
+		return Result{Status: StatusDown, LatencyMs: latency, Error: err.Error()}
+	}
+
+	return Result{Status: StatusUp, LatencyMs: latency}
+}
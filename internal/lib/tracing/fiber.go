@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const fiberScope = "wb-internship-l0/internal/lib/tracing"
+
+// Middleware starts a server span for every request, continuing the trace of the caller.
+// The span is available to handlers through c.UserContext().
+func Middleware() fiber.Handler {
+	tracer := otel.Tracer(fiberScope)
+
+	return func(c *fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), requestCarrier{&c.Request().Header})
+
+		ctx, span := tracer.Start(ctx, c.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Method()),
+				semconv.URLPath(c.Path()),
+				semconv.ClientAddress(c.IP()),
+			),
+		)
+		defer span.End()
+
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+			span.RecordError(err)
+		}
+
+		// The route is known only once the request is matched.
+		route := c.Route().Path
+		span.SetName(c.Method() + " " + route)
+		span.SetAttributes(
+			semconv.HTTPRoute(route),
+			semconv.HTTPResponseStatusCode(status),
+		)
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, fasthttp.StatusMessage(status))
+		}
+
+		return err
+	}
+}
+
+// requestCarrier adapts request headers to propagation.TextMapCarrier.
+type requestCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+func (r requestCarrier) Get(key string) string {
+	return string(r.header.Peek(key))
+}
+
+func (r requestCarrier) Set(key, value string) {
+	r.header.Set(key, value)
+}
+
+func (r requestCarrier) Keys() []string {
+	keys := make([]string, 0, r.header.Len())
+	r.header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+
+	return keys
+}
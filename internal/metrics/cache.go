@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"wb-internship-l0/pkg/cache"
+)
+
+// instrumentedCache counts hits and misses of the wrapped cache.
+type instrumentedCache[K comparable, V any] struct {
+	cache.Cache[K, V]
+	hits   prometheus.Counter
+	misses prometheus.Counter
+}
+
+// InstrumentCache returns the cache counting its hits and misses.
+func InstrumentCache[K comparable, V any](m *Metrics, c cache.Cache[K, V]) cache.Cache[K, V] {
+	return &instrumentedCache[K, V]{
+		Cache:  c,
+		hits:   m.cacheHits,
+		misses: m.cacheMisses,
+	}
+}
+
+// Get retrieves a cached value by its key and counts the result.
+func (i *instrumentedCache[K, V]) Get(key K) (V, bool) {
+	value, ok := i.Cache.Get(key)
+	if ok {
+		i.hits.Inc()
+	} else {
+		i.misses.Inc()
+	}
+
+	return value, ok
+}
+
+// RegisterCache exposes the size and the evictions of the local cache, if it reports them.
+func (m *Metrics) RegisterCache(c any) {
+	if sized, ok := c.(interface{ Len() int }); ok {
+		m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "entries",
+			Help:      "Number of entries in the local order cache.",
+		}, func() float64 {
+			return float64(sized.Len())
+		}))
+	}
+
+	if bounded, ok := c.(interface{ Evictions() uint64 }); ok {
+		m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "evictions_total",
+			Help:      "Number of entries evicted from the local order cache to stay within its limits.",
+		}, func() float64 {
+			return float64(bounded.Evictions())
+		}))
+	}
+}
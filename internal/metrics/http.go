@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"time"
+)
+
+// Middleware counts requests and measures their latency by route pattern, so that
+// path parameters such as order UIDs don't create a series per value.
+func (m *Metrics) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			// The status is set by the error handler after the middleware returns.
+			status = fiber.StatusInternalServerError
+
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+
+		labels := []string{c.Method(), c.Route().Path, strconv.Itoa(status)}
+		m.httpRequests.WithLabelValues(labels...).Inc()
+		m.httpDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"strconv"
+)
+
+const namespace = "l0"
+
+// Metrics holds the service metrics exposed in the Prometheus text format.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	messagesProcessed *prometheus.CounterVec
+	messagesFailed    *prometheus.CounterVec
+	consumerLag       *prometheus.GaugeVec
+
+	cacheHits   prometheus.Counter
+	cacheMisses prometheus.Counter
+}
+
+// New returns a new instance of Metrics with the Go runtime and process metrics registered.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of handled HTTP requests.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Time spent handling HTTP requests.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"method", "route", "status"}),
+
+		messagesProcessed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "consumer",
+			Name:      "messages_processed_total",
+			Help:      "Number of successfully processed messages by event type.",
+		}, []string{"event"}),
+		messagesFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "consumer",
+			Name:      "messages_failed_total",
+			Help:      "Number of rejected messages by rejection reason.",
+		}, []string{"reason"}),
+		consumerLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "consumer",
+			Name:      "lag",
+			Help:      "Number of messages behind the end of the partition as of the last fetched message.",
+		}, []string{"partition"}),
+
+		cacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "hits_total",
+			Help:      "Number of order cache lookups that found the order.",
+		}),
+		cacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "misses_total",
+			Help:      "Number of order cache lookups that didn't find the order.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.messagesProcessed,
+		m.messagesFailed,
+		m.consumerLag,
+		m.cacheHits,
+		m.cacheMisses,
+	)
+
+	return m
+}
+
+// Handler returns the handler serving the metrics.
+func (m *Metrics) Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+// MessageProcessed counts a successfully processed message.
+func (m *Metrics) MessageProcessed(event string) {
+	m.messagesProcessed.WithLabelValues(event).Inc()
+}
+
+// MessageFailed counts a rejected message.
+func (m *Metrics) MessageFailed(reason string) {
+	m.messagesFailed.WithLabelValues(reason).Inc()
+}
+
+// Lag records the consumer lag of a partition.
+func (m *Metrics) Lag(partition int, lag int64) {
+	m.consumerLag.WithLabelValues(strconv.Itoa(partition)).Set(float64(lag))
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StatProvider is implemented by pgxpool.Pool.
+type StatProvider interface {
+	Stat() *pgxpool.Stat
+}
+
+// poolCollector reads the connection pool statistics on every scrape.
+type poolCollector struct {
+	pool StatProvider
+
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	acquiredConns        *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	idleConns            *prometheus.Desc
+	maxConns             *prometheus.Desc
+	totalConns           *prometheus.Desc
+	newConnsCount        *prometheus.Desc
+	maxLifetimeDestroy   *prometheus.Desc
+	maxIdleDestroy       *prometheus.Desc
+}
+
+// RegisterPool exposes the statistics of the database connection pool.
+func (m *Metrics) RegisterPool(pool StatProvider) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+
+	m.registry.MustRegister(&poolCollector{
+		pool: pool,
+
+		acquireCount:         desc("acquires_total", "Number of successful connection acquires."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Time spent on successful connection acquires."),
+		acquiredConns:        desc("acquired_connections", "Number of connections in use."),
+		canceledAcquireCount: desc("canceled_acquires_total", "Number of acquires canceled by a context."),
+		constructingConns:    desc("constructing_connections", "Number of connections being established."),
+		emptyAcquireCount:    desc("empty_acquires_total", "Number of acquires that waited for a connection."),
+		idleConns:            desc("idle_connections", "Number of idle connections."),
+		maxConns:             desc("max_connections", "Maximum size of the pool."),
+		totalConns:           desc("connections", "Total number of connections."),
+		newConnsCount:        desc("new_connections_total", "Number of established connections."),
+		maxLifetimeDestroy:   desc("max_lifetime_destroys_total", "Number of connections closed for exceeding their lifetime."),
+		maxIdleDestroy:       desc("max_idle_destroys_total", "Number of connections closed for being idle too long."),
+	})
+}
+
+// Describe implements prometheus.Collector.
+func (p *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(p, ch)
+}
+
+// Collect implements prometheus.Collector.
+func (p *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := p.pool.Stat()
+
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+
+	counter(p.acquireCount, float64(stat.AcquireCount()))
+	counter(p.acquireDuration, stat.AcquireDuration().Seconds())
+	gauge(p.acquiredConns, float64(stat.AcquiredConns()))
+	counter(p.canceledAcquireCount, float64(stat.CanceledAcquireCount()))
+	gauge(p.constructingConns, float64(stat.ConstructingConns()))
+	counter(p.emptyAcquireCount, float64(stat.EmptyAcquireCount()))
+	gauge(p.idleConns, float64(stat.IdleConns()))
+	gauge(p.maxConns, float64(stat.MaxConns()))
+	gauge(p.totalConns, float64(stat.TotalConns()))
+	counter(p.newConnsCount, float64(stat.NewConnsCount()))
+	counter(p.maxLifetimeDestroy, float64(stat.MaxLifetimeDestroyCount()))
+	counter(p.maxIdleDestroy, float64(stat.MaxIdleDestroyCount()))
+}
@@ -7,13 +7,20 @@ import (
 	"fmt"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/attribute"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 	"wb-internship-l0/internal/entity"
 	postgres "wb-internship-l0/internal/lib/pg"
+	"wb-internship-l0/internal/lib/tracing"
 )
 
 var (
 	ErrOrderAlreadyExists = errors.New("order already exists")
 	ErrOrderNotFound      = errors.New("order not found")
+	ErrVersionConflict    = errors.New("order was modified concurrently")
 )
 
 // OrderRepository is a repository for managing orders in the database.
@@ -31,7 +38,15 @@ func NewOrderRepository(pg *postgres.Postgres) *OrderRepository {
 func (r *OrderRepository) AddOrder(ctx context.Context, id string, data json.RawMessage) error {
 	const op = "repository.order.AddOrder"
 
-	query := `INSERT INTO orders_schema.order(OrderID, Data) VALUES(@id, @data)`
+	ctx, span := startSpan(ctx, op, attribute.String("order.uid", id))
+	defer span.End()
+
+	query := `WITH inserted AS (
+			INSERT INTO orders_schema.order(OrderID, Data) VALUES(@id, @data)
+			RETURNING OrderID, Version, Status, Data
+		)
+		INSERT INTO orders_schema.order_history(OrderID, Version, Status, Data)
+		SELECT OrderID, Version, Status, Data FROM inserted`
 	args := pgx.NamedArgs{
 		"id":   id,
 		"data": data,
@@ -41,73 +56,449 @@ func (r *OrderRepository) AddOrder(ctx context.Context, id string, data json.Raw
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			span.AddEvent("order already exists")
 			return fmt.Errorf("%s: %w", op, ErrOrderAlreadyExists)
 		}
-		return fmt.Errorf("%s: %w", op, err)
+		return tracing.Fail(span, fmt.Errorf("%s: %w", op, err))
 	}
 
 	return nil
 }
 
+// AddOrders inserts several orders to the database in a single batch.
+// Orders that already exist are skipped. Returns a slice reporting for every order
+// whether it was inserted, or an error if the batch fails.
+func (r *OrderRepository) AddOrders(ctx context.Context, orders []entity.Order) ([]bool, error) {
+	const op = "repository.order.AddOrders"
+
+	ctx, span := startSpan(ctx, op, attribute.Int("orders.count", len(orders)))
+	defer span.End()
+
+	query := `WITH inserted AS (
+			INSERT INTO orders_schema.order(OrderID, Data) VALUES(@id, @data)
+			ON CONFLICT (OrderID) DO NOTHING
+			RETURNING OrderID, Version, Status, Data
+		)
+		INSERT INTO orders_schema.order_history(OrderID, Version, Status, Data)
+		SELECT OrderID, Version, Status, Data FROM inserted`
+
+	batch := &pgx.Batch{}
+	for _, order := range orders {
+		batch.Queue(query, pgx.NamedArgs{
+			"id":   order.UID,
+			"data": order.Data,
+		})
+	}
+
+	results := r.DB.SendBatch(ctx, batch)
+
+	inserted := make([]bool, len(orders))
+	for i := range orders {
+		tag, err := results.Exec()
+		if err != nil {
+			_ = results.Close()
+			return nil, tracing.Fail(span, fmt.Errorf("%s: %w", op, err))
+		}
+		inserted[i] = tag.RowsAffected() == 1
+	}
+
+	if err := results.Close(); err != nil {
+		return nil, tracing.Fail(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return inserted, nil
+}
+
+// UpdateOrder replaces the data and status of an existing order and records the new
+// state in the order history within the same transaction.
+// The order is only updated if its stored version is still order.Version, so a change decided
+// on a stale read is never applied. The order version is incremented when bumpVersion is true;
+// otherwise the new state is recorded under the kept version. History is append-only, so a version
+// may have several entries, ordered by ID.
+// Returns the updated entity.Order, ErrOrderNotFound if there is no such order,
+// or ErrVersionConflict if the order was changed since it was read.
+func (r *OrderRepository) UpdateOrder(ctx context.Context, order entity.Order, bumpVersion bool) (entity.Order, error) {
+	const op = "repository.order.UpdateOrder"
+
+	ctx, span := startSpan(ctx, op, attribute.String("order.uid", order.UID))
+	defer span.End()
+
+	updateQuery := `UPDATE orders_schema.order
+		SET Data = @data, Status = @status, Version = CASE WHEN @bump THEN Version + 1 ELSE Version END
+		WHERE OrderID = @id AND Version = @expected
+		RETURNING Version`
+	existsQuery := `SELECT EXISTS(SELECT 1 FROM orders_schema.order WHERE OrderID = @id)`
+	historyQuery := `INSERT INTO orders_schema.order_history(OrderID, Version, Status, Data)
+		VALUES(@id, @version, @status, @data)`
+
+	err := pgx.BeginFunc(ctx, r.DB, func(tx pgx.Tx) error {
+		args := pgx.NamedArgs{
+			"id":       order.UID,
+			"data":     order.Data,
+			"status":   order.Status,
+			"bump":     bumpVersion,
+			"expected": order.Version,
+		}
+		err := tx.QueryRow(ctx, updateQuery, args).Scan(&order.Version)
+		if errors.Is(err, pgx.ErrNoRows) {
+			var exists bool
+			if err := tx.QueryRow(ctx, existsQuery, pgx.NamedArgs{"id": order.UID}).Scan(&exists); err != nil {
+				return err
+			}
+			if exists {
+				return ErrVersionConflict
+			}
+
+			return ErrOrderNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		args = pgx.NamedArgs{
+			"id":      order.UID,
+			"version": order.Version,
+			"status":  order.Status,
+			"data":    order.Data,
+		}
+		_, err = tx.Exec(ctx, historyQuery, args)
+
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrOrderNotFound) || errors.Is(err, ErrVersionConflict) {
+			return entity.Order{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return entity.Order{}, tracing.Fail(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	return order, nil
+}
+
 // GetOrder retrieves an order by its uid from the database.
 // Returns entity.Order if the order is found, or an error if errors are occurred.
 func (r *OrderRepository) GetOrder(ctx context.Context, id string) (entity.Order, error) {
 	const op = "repository.order.GetOrder"
 
-	var data json.RawMessage
+	ctx, span := startSpan(ctx, op, attribute.String("order.uid", id))
+	defer span.End()
 
-	query := `SELECT Data FROM orders_schema.order WHERE OrderID = @id`
+	var (
+		version int
+		status  entity.OrderStatus
+		data    json.RawMessage
+	)
+
+	query := `SELECT Version, Status, Data FROM orders_schema.order WHERE OrderID = @id`
 	args := pgx.NamedArgs{
 		"id": id,
 	}
-	err := r.DB.QueryRow(ctx, query, args).Scan(&data)
+	err := r.DB.QueryRow(ctx, query, args).Scan(&version, &status, &data)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return entity.Order{}, fmt.Errorf("%s: %w", op, ErrOrderNotFound)
 		}
 
-		return entity.Order{}, fmt.Errorf("%s: %w", op, err)
+		return entity.Order{}, tracing.Fail(span, fmt.Errorf("%s: %w", op, err))
 	}
 
 	order := entity.Order{
-		UID:  id,
-		Data: data,
+		UID:     id,
+		Version: version,
+		Status:  status,
+		Data:    data,
 	}
 
 	return order, nil
 }
 
-func (r *OrderRepository) GetAllOrders(ctx context.Context) ([]entity.Order, error) {
-	const op = "repository.order.GetAllOrders"
+// IterateOrders streams orders from the database to fn without loading them all into memory.
+// Only orders created since the given moment are read unless it is zero, and at most the limit
+// newest orders are read unless it is zero. Orders are passed oldest first, so that a size-bounded
+// cache filled in this order evicts the oldest ones. Iteration stops at the first error returned by fn.
+func (r *OrderRepository) IterateOrders(ctx context.Context, since time.Time, limit int, fn func(entity.Order) error) error {
+	const op = "repository.order.IterateOrders"
+
+	query := `SELECT OrderID, Version, Status, Data, DateCreated FROM orders_schema.order`
+	args := pgx.NamedArgs{}
+
+	if !since.IsZero() {
+		query += ` WHERE DateCreated >= @since`
+		args["since"] = since
+	}
+	query += ` ORDER BY DateCreated DESC NULLS LAST, OrderID DESC`
+	if limit > 0 {
+		query += ` LIMIT @limit`
+		args["limit"] = limit
+	}
+
+	// The newest orders are selected first and then reversed.
+	query = `SELECT OrderID, Version, Status, Data FROM (` + query + `) recent
+		ORDER BY DateCreated ASC NULLS FIRST, OrderID ASC`
+
+	rows, err := r.DB.Query(ctx, query, args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var order entity.Order
+
+		if err := rows.Scan(&order.UID, &order.Version, &order.Status, &order.Data); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := fn(order); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if rows.Err() != nil {
+		return fmt.Errorf("%s: %w", op, rows.Err())
+	}
+
+	return nil
+}
+
+// IterateOrdersChangedSince streams the current state of every order that was created
+// or updated after since, calling fn for each of them.
+func (r *OrderRepository) IterateOrdersChangedSince(ctx context.Context, since time.Time, fn func(entity.Order) error) error {
+	const op = "repository.order.IterateOrdersChangedSince"
+
+	query := `SELECT o.OrderID, o.Version, o.Status, o.Data FROM orders_schema.order o
+		WHERE o.OrderID IN (
+			SELECT h.OrderID FROM orders_schema.order_history h WHERE h.RecordedAt > @since
+		)`
+
+	rows, err := r.DB.Query(ctx, query, pgx.NamedArgs{"since": since})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var order entity.Order
+
+		if err := rows.Scan(&order.UID, &order.Version, &order.Status, &order.Data); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := fn(order); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if rows.Err() != nil {
+		return fmt.Errorf("%s: %w", op, rows.Err())
+	}
+
+	return nil
+}
+
+// GetOrderHistory retrieves every recorded state of an order, oldest first.
+// Returns ErrOrderNotFound if the order has no history.
+func (r *OrderRepository) GetOrderHistory(ctx context.Context, id string) ([]entity.OrderVersion, error) {
+	const op = "repository.order.GetOrderHistory"
 
-	query := `SELECT OrderID, Data FROM orders_schema.order`
+	query := `SELECT Version, Status, Data, RecordedAt FROM orders_schema.order_history
+		WHERE OrderID = @id
+		ORDER BY RecordedAt, ID`
+	args := pgx.NamedArgs{
+		"id": id,
+	}
 
-	rows, err := r.DB.Query(ctx, query)
+	rows, err := r.DB.Query(ctx, query, args)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 	defer rows.Close()
 
-	var orders []entity.Order
+	var history []entity.OrderVersion
 
 	for rows.Next() {
-		var id string
-		var data json.RawMessage
+		version := entity.OrderVersion{Order: entity.Order{UID: id}}
 
-		if err := rows.Scan(&id, &data); err != nil {
+		if err := rows.Scan(&version.Version, &version.Status, &version.Data, &version.RecordedAt); err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 
-		order := entity.Order{
-			UID:  id,
-			Data: data,
+		history = append(history, version)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("%s: %w", op, rows.Err())
+	}
+
+	if len(history) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrOrderNotFound)
+	}
+
+	return history, nil
+}
+
+// GetOrderAsOf retrieves the state an order had at the given moment.
+// Returns ErrOrderNotFound if the order didn't exist at that moment.
+func (r *OrderRepository) GetOrderAsOf(ctx context.Context, id string, asOf time.Time) (entity.OrderVersion, error) {
+	const op = "repository.order.GetOrderAsOf"
+
+	query := `SELECT Version, Status, Data, RecordedAt FROM orders_schema.order_history
+		WHERE OrderID = @id AND RecordedAt <= @as_of
+		ORDER BY RecordedAt DESC, ID DESC
+		LIMIT 1`
+	args := pgx.NamedArgs{
+		"id":    id,
+		"as_of": asOf,
+	}
+
+	version := entity.OrderVersion{Order: entity.Order{UID: id}}
+
+	err := r.DB.QueryRow(ctx, query, args).Scan(&version.Version, &version.Status, &version.Data, &version.RecordedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.OrderVersion{}, fmt.Errorf("%s: %w", op, ErrOrderNotFound)
 		}
+
+		return entity.OrderVersion{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
+
+// ListOrders retrieves a page of orders matching the filter, newest first.
+// Pages are addressed with a keyset cursor, so listing stays fast regardless of the page depth.
+// Orders with a creation date are listed first; orders without one follow, ordered by UID,
+// and are read in a separate query so that both phases are bounded by the index.
+func (r *OrderRepository) ListOrders(ctx context.Context, filter entity.OrderFilter) (entity.OrderPage, error) {
+	const op = "repository.order.ListOrders"
+
+	conditions := make([]string, 0, 8)
+	args := pgx.NamedArgs{}
+
+	columns := []struct {
+		column string
+		value  string
+	}{
+		{"CustomerID", filter.CustomerID},
+		{"TrackNumber", filter.TrackNumber},
+		{"DeliveryService", filter.DeliveryService},
+		{"Locale", filter.Locale},
+		{"PaymentProvider", filter.PaymentProvider},
+	}
+	for _, c := range columns {
+		if c.value == "" {
+			continue
+		}
+		name := strings.ToLower(c.column)
+		conditions = append(conditions, fmt.Sprintf("%s = @%s", c.column, name))
+		args[name] = c.value
+	}
+
+	dateFiltered := false
+	if !filter.CreatedFrom.IsZero() {
+		conditions = append(conditions, "DateCreated >= @created_from")
+		args["created_from"] = filter.CreatedFrom
+		dateFiltered = true
+	}
+	if !filter.CreatedTo.IsZero() {
+		conditions = append(conditions, "DateCreated < @created_to")
+		args["created_to"] = filter.CreatedTo
+		dateFiltered = true
+	}
+
+	var (
+		page    entity.OrderPage
+		created []time.Time
+	)
+
+	// Orders with a creation date, unless the cursor is already past them.
+	if filter.After == nil || !filter.After.DateCreated.IsZero() {
+		dated := append(slices.Clip(conditions), "DateCreated IS NOT NULL")
+		datedArgs := maps.Clone(args)
+		if filter.After != nil {
+			dated = append(dated, "(DateCreated, OrderID) < (@after_date, @after_id)")
+			datedArgs["after_date"] = filter.After.DateCreated
+			datedArgs["after_id"] = filter.After.UID
+		}
+
+		orders, dates, err := r.listOrderRows(ctx, dated, datedArgs, filter.Limit+1)
+		if err != nil {
+			return entity.OrderPage{}, fmt.Errorf("%s: %w", op, err)
+		}
+		page.Orders = append(page.Orders, orders...)
+		created = append(created, dates...)
+	}
+
+	// Orders without a creation date never match a date filter.
+	if len(page.Orders) <= filter.Limit && !dateFiltered {
+		undated := append(slices.Clip(conditions), "DateCreated IS NULL")
+		undatedArgs := maps.Clone(args)
+		if filter.After != nil && filter.After.DateCreated.IsZero() {
+			undated = append(undated, "OrderID < @after_id")
+			undatedArgs["after_id"] = filter.After.UID
+		}
+
+		orders, dates, err := r.listOrderRows(ctx, undated, undatedArgs, filter.Limit+1-len(page.Orders))
+		if err != nil {
+			return entity.OrderPage{}, fmt.Errorf("%s: %w", op, err)
+		}
+		page.Orders = append(page.Orders, orders...)
+		created = append(created, dates...)
+	}
+
+	if len(page.Orders) > filter.Limit {
+		page.Orders = page.Orders[:filter.Limit]
+		last := len(page.Orders) - 1
+		page.Next = &entity.OrderCursor{
+			DateCreated: created[last],
+			UID:         page.Orders[last].UID,
+		}
+	}
+
+	return page, nil
+}
+
+// listOrderRows reads at most limit orders matching the conditions in listing order,
+// along with their creation dates; a missing date is returned as zero.
+func (r *OrderRepository) listOrderRows(ctx context.Context, conditions []string, args pgx.NamedArgs, limit int) ([]entity.Order, []time.Time, error) {
+	args["limit"] = limit
+
+	query := `SELECT OrderID, Version, Status, Data, DateCreated FROM orders_schema.order
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY DateCreated DESC NULLS LAST, OrderID DESC
+		LIMIT @limit`
+
+	rows, err := r.DB.Query(ctx, query, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var (
+		orders  []entity.Order
+		created []time.Time
+	)
+
+	for rows.Next() {
+		var order entity.Order
+		var dateCreated *time.Time
+
+		if err := rows.Scan(&order.UID, &order.Version, &order.Status, &order.Data, &dateCreated); err != nil {
+			return nil, nil, err
+		}
+
 		orders = append(orders, order)
+		if dateCreated != nil {
+			created = append(created, *dateCreated)
+		} else {
+			created = append(created, time.Time{})
+		}
 	}
 
 	if rows.Err() != nil {
-		return nil, fmt.Errorf("%s: %w", op, rows.Err())
+		return nil, nil, rows.Err()
 	}
 
-	return orders, nil
+	return orders, created, nil
 }
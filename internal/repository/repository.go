@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"time"
 	"wb-internship-l0/internal/entity"
 	postgres "wb-internship-l0/internal/lib/pg"
 	"wb-internship-l0/internal/repository/pgdb"
@@ -11,8 +12,14 @@ import (
 // Order defines an interface for order-related operations.
 type Order interface {
 	AddOrder(ctx context.Context, id string, data json.RawMessage) error
+	AddOrders(ctx context.Context, orders []entity.Order) ([]bool, error)
+	UpdateOrder(ctx context.Context, order entity.Order, bumpVersion bool) (entity.Order, error)
 	GetOrder(ctx context.Context, id string) (entity.Order, error)
-	GetAllOrders(ctx context.Context) ([]entity.Order, error)
+	IterateOrders(ctx context.Context, since time.Time, limit int, fn func(entity.Order) error) error
+	IterateOrdersChangedSince(ctx context.Context, since time.Time, fn func(entity.Order) error) error
+	ListOrders(ctx context.Context, filter entity.OrderFilter) (entity.OrderPage, error)
+	GetOrderHistory(ctx context.Context, id string) ([]entity.OrderVersion, error)
+	GetOrderAsOf(ctx context.Context, id string, asOf time.Time) (entity.OrderVersion, error)
 }
 
 // Repositories is a struct that aggregates various repositories.
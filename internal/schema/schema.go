@@ -0,0 +1,155 @@
+// Package schema holds the versioned JSON Schema documents of incoming messages
+// and validates messages against them.
+package schema
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"strings"
+)
+
+// OrderVersion is the version of the order schema messages are validated against.
+const OrderVersion = "v1"
+
+//go:embed order.v1.json
+var orderV1 []byte
+
+// orderSchemas holds every published version of the order schema.
+var orderSchemas = map[string][]byte{
+	"v1": orderV1,
+}
+
+var ErrInvalidJSON = errors.New("document is not valid JSON")
+
+// orderValidator is compiled from the embedded schema of OrderVersion.
+var orderValidator = mustCompile(orderSchemas[OrderVersion])
+
+// Order returns the order schema document of the given version.
+func Order(version string) ([]byte, bool) {
+	doc, ok := orderSchemas[version]
+	return doc, ok
+}
+
+// Violation describes a single part of a document not matching the schema.
+type Violation struct {
+	// Field is the dotted path of the field, e.g. "items.0.sale".
+	Field   string
+	Keyword string
+	Message string
+}
+
+// ValidationError is returned when a document doesn't match the schema.
+// Missing lists required fields that are absent, Invalid lists present fields with wrong values.
+type ValidationError struct {
+	Version string
+	Missing []Violation
+	Invalid []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Missing)+len(e.Invalid))
+	for _, v := range e.Missing {
+		msgs = append(msgs, v.Field+" is required")
+	}
+	for _, v := range e.Invalid {
+		msgs = append(msgs, v.Field+": "+v.Message)
+	}
+
+	return fmt.Sprintf("order schema %s: %s", e.Version, strings.Join(msgs, "; "))
+}
+
+// ValidateOrder validates a raw order document against the current order schema.
+// It returns ErrInvalidJSON if the document can't be parsed, or a *ValidationError.
+func ValidateOrder(data []byte) error {
+	const op = "schema.ValidateOrder"
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", op, ErrInvalidJSON, err)
+	}
+
+	err = orderValidator.Validate(doc)
+	if err == nil {
+		return nil
+	}
+
+	var schemaErr *jsonschema.ValidationError
+	if !errors.As(err, &schemaErr) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	validationErr := &ValidationError{Version: OrderVersion}
+	collect(validationErr, schemaErr)
+
+	return fmt.Errorf("%s: %w", op, validationErr)
+}
+
+var printer = message.NewPrinter(language.English)
+
+// collect adds the leaf errors of the tree to the validation error.
+func collect(dst *ValidationError, err *jsonschema.ValidationError) {
+	if len(err.Causes) > 0 {
+		for _, cause := range err.Causes {
+			collect(dst, cause)
+		}
+
+		return
+	}
+
+	field := strings.Join(err.InstanceLocation, ".")
+	keyword := strings.Join(err.ErrorKind.KeywordPath(), "/")
+
+	if missing, ok := err.ErrorKind.(*kind.Required); ok {
+		for _, name := range missing.Missing {
+			dst.Missing = append(dst.Missing, Violation{
+				Field:   joinField(field, name),
+				Keyword: keyword,
+				Message: "is required",
+			})
+		}
+
+		return
+	}
+
+	dst.Invalid = append(dst.Invalid, Violation{
+		Field:   field,
+		Keyword: keyword,
+		Message: err.ErrorKind.LocalizedString(printer),
+	})
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+
+	return parent + "." + name
+}
+
+func mustCompile(doc []byte) *jsonschema.Schema {
+	parsed, err := jsonschema.UnmarshalJSON(bytes.NewReader(doc))
+	if err != nil {
+		panic(fmt.Sprintf("schema: embedded schema is not valid JSON: %v", err))
+	}
+
+	id, _ := parsed.(map[string]any)["$id"].(string)
+
+	c := jsonschema.NewCompiler()
+	c.AssertFormat()
+	if err := c.AddResource(id, parsed); err != nil {
+		panic(fmt.Sprintf("schema: %v", err))
+	}
+
+	sch, err := c.Compile(id)
+	if err != nil {
+		panic(fmt.Sprintf("schema: %v", err))
+	}
+
+	return sch
+}
@@ -0,0 +1,116 @@
+package schema_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"wb-internship-l0/internal/schema"
+)
+
+// testOrder is a valid order with zero sale, delivery cost and custom fee.
+const testOrder = `{
+	"order_uid": "b563feb7b2b84b6test",
+	"track_number": "WBILMTESTTRACK",
+	"entry": "WBIL",
+	"delivery": {
+		"name": "Test Testov",
+		"phone": "+9720000000",
+		"zip": "2639809",
+		"city": "Kiryat Mozkin",
+		"address": "Ploshad Mira 15",
+		"region": "Kraiot",
+		"email": "test@gmail.com"
+	},
+	"payment": {
+		"transaction": "b563feb7b2b84b6test",
+		"request_id": "",
+		"currency": "USD",
+		"provider": "wbpay",
+		"amount": 317,
+		"payment_dt": 1637907727,
+		"bank": "alpha",
+		"delivery_cost": 0,
+		"goods_total": 317,
+		"custom_fee": 0
+	},
+	"items": [{
+		"chrt_id": 9934930,
+		"track_number": "WBILMTESTTRACK",
+		"price": 317,
+		"rid": "ab4219087a764ae0btest",
+		"name": "Mascaras",
+		"sale": 0,
+		"size": "0",
+		"total_price": 317,
+		"nm_id": 2389212,
+		"brand": "Vivienne Sabo",
+		"status": 202
+	}],
+	"locale": "en",
+	"internal_signature": "",
+	"customer_id": "test",
+	"delivery_service": "meest",
+	"shardkey": "9",
+	"sm_id": 99,
+	"date_created": "2021-11-26T06:22:19Z",
+	"oof_shard": "1"
+}`
+
+func TestValidateOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		missing []string
+		invalid []string
+	}{
+		{
+			name: "zero values",
+			data: testOrder,
+		},
+		{
+			name:    "missing field",
+			data:    strings.Replace(testOrder, `"delivery_cost": 0,`, ``, 1),
+			missing: []string{"payment.delivery_cost"},
+		},
+		{
+			name:    "wrong type",
+			data:    strings.Replace(testOrder, `"sale": 0,`, `"sale": "0",`, 1),
+			invalid: []string{"items.0.sale"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.ValidateOrder([]byte(tt.data))
+
+			if tt.missing == nil && tt.invalid == nil {
+				if err != nil {
+					t.Fatalf("ValidateOrder() = %v; want nil", err)
+				}
+				return
+			}
+
+			var validationErr *schema.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("ValidateOrder() = %v; want a *ValidationError", err)
+			}
+
+			if got := fields(validationErr.Missing); !slices.Equal(got, tt.missing) {
+				t.Errorf("Missing = %v; want %v", got, tt.missing)
+			}
+			if got := fields(validationErr.Invalid); !slices.Equal(got, tt.invalid) {
+				t.Errorf("Invalid = %v; want %v", got, tt.invalid)
+			}
+		})
+	}
+}
+
+func fields(violations []schema.Violation) []string {
+	names := make([]string, 0, len(violations))
+	for _, v := range violations {
+		names = append(names, v.Field)
+	}
+
+	return names
+}
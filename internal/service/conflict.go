@@ -0,0 +1,47 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// ConflictPolicy defines how a redelivered order with a different payload is handled.
+type ConflictPolicy string
+
+const (
+	// ConflictReject keeps the stored order and reports ErrOrderConflict.
+	ConflictReject ConflictPolicy = "reject"
+	// ConflictOverwrite replaces the stored payload keeping its version; the new state is appended
+	// to the history under the same version.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictVersion replaces the stored payload and increments the order version.
+	ConflictVersion ConflictPolicy = "version"
+)
+
+// ParseConflictPolicy converts a configuration value to a ConflictPolicy.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	const op = "service.ParseConflictPolicy"
+
+	switch p := ConflictPolicy(s); p {
+	case ConflictReject, ConflictOverwrite, ConflictVersion:
+		return p, nil
+	default:
+		return "", fmt.Errorf("%s: unknown conflict policy %q", op, s)
+	}
+}
+
+// samePayload reports whether two JSON documents are semantically equal,
+// ignoring key order and whitespace that Postgres JSONB does not preserve.
+func samePayload(a, b json.RawMessage) bool {
+	var left, right any
+
+	if err := json.Unmarshal(a, &left); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(b, &right); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(left, right)
+}
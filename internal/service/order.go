@@ -5,7 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+	"time"
+	"wb-internship-l0/internal/entity"
+	"wb-internship-l0/internal/lib/tracing"
 	"wb-internship-l0/internal/repository"
 	"wb-internship-l0/internal/repository/pgdb"
 	"wb-internship-l0/pkg/cache"
@@ -14,39 +21,81 @@ import (
 var (
 	ErrOrderAlreadyExists = errors.New("order already exists")
 	ErrOrderNotFound      = errors.New("order not found")
+	ErrOrderConflict      = errors.New("order already exists with a different payload")
+	ErrUnknownStatus      = errors.New("unknown order status")
+	ErrInvalidTransition  = errors.New("order status transition is not allowed")
+	ErrConcurrentUpdate   = errors.New("order is being updated concurrently")
 )
 
+var tracer = otel.Tracer("wb-internship-l0/internal/service")
+
+// Page size limits of ListOrders.
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// maxMissingEntries bounds the number of remembered nonexistent UIDs.
+const maxMissingEntries = 100_000
+
+// maxUpdateAttempts bounds how many times UpdateOrder re-reads an order changed concurrently.
+const maxUpdateAttempts = 5
+
 // OrderService provides methods to manage orders.
 type OrderService struct {
-	Log   *zap.Logger
-	Cache cache.Cache
-	Repo  repository.Order
+	Log      *zap.Logger
+	Cache    cache.Cache[string, json.RawMessage]
+	Repo     repository.Order
+	Conflict ConflictPolicy
+
+	// missing remembers UIDs recently not found in the database.
+	missing cache.Cache[string, struct{}]
+	// loads coalesces concurrent database reads of the same order.
+	loads singleflight.Group
 }
 
 // NewOrderService initializes and returns a new OrderService.
-func NewOrderService(log *zap.Logger, cache cache.Cache, repo repository.Order) *OrderService {
-	return &OrderService{
-		Log:   log,
-		Cache: cache,
-		Repo:  repo,
+// Nonexistent UIDs are remembered for notFoundTTL, a zero TTL disables negative caching.
+func NewOrderService(log *zap.Logger, cache cache.Cache[string, json.RawMessage], repo repository.Order, conflict ConflictPolicy, notFoundTTL time.Duration) *OrderService {
+	s := &OrderService{
+		Log:      log,
+		Cache:    cache,
+		Repo:     repo,
+		Conflict: conflict,
 	}
+
+	if notFoundTTL > 0 {
+		s.missing = newMissingCache(notFoundTTL)
+	}
+
+	return s
+}
+
+func newMissingCache(ttl time.Duration) cache.Cache[string, struct{}] {
+	return cache.NewLRUCache[string, struct{}](maxMissingEntries, 0, cache.WithDefaultTTL(ttl))
 }
 
 // SaveOrder saves a new order to the database and cache.
+// Redelivery of an already stored order with the same payload is treated as success,
+// a different payload is handled according to the configured ConflictPolicy.
 func (s *OrderService) SaveOrder(ctx context.Context, id string, data json.RawMessage) error {
 	const op = "service.OrderService.SaveOrder"
 
+	ctx, span := tracer.Start(ctx, op, trace.WithAttributes(attribute.String("order.uid", id)))
+	defer span.End()
+
 	s.Log.Info("Attempting to save order")
 
 	err := s.Repo.AddOrder(ctx, id, data)
 	if err != nil {
 		if errors.Is(err, pgdb.ErrOrderAlreadyExists) {
-			s.Log.Warn("Order already exists",
-				zap.String("op", op),
-				zap.String("orderID", id),
-			)
+			span.AddEvent("duplicate order")
+
+			if err := s.resolveDuplicate(ctx, id, data); err != nil {
+				return tracing.Fail(span, err)
+			}
 
-			return fmt.Errorf("%s: %w", op, ErrOrderAlreadyExists)
+			return nil
 		}
 
 		s.Log.Error("Failed to save order to database",
@@ -55,20 +104,23 @@ func (s *OrderService) SaveOrder(ctx context.Context, id string, data json.RawMe
 			zap.Error(err),
 		)
 
-		return fmt.Errorf("%s: %w", op, err)
+		return tracing.Fail(span, fmt.Errorf("%s: %w", op, err))
 	}
 
 	s.Log.Info("Order successfully saved to database")
 
-	err = s.Cache.Set(id, data)
-	if err != nil {
+	s.forgetMissing(id)
+
+	// The order may have been cached already by a concurrent read-through.
+	err = s.Cache.Set(id, withStatus(entity.Order{UID: id, Data: data}))
+	if err != nil && !errors.Is(err, cache.ErrKeyAlreadyExists) {
 		s.Log.Warn("Failed to save order to cache",
 			zap.String("op", op),
 			zap.String("orderID", id),
 			zap.Error(err),
 		)
 
-		return fmt.Errorf("%s: %w", op, err)
+		return tracing.Fail(span, fmt.Errorf("%s: %w", op, err))
 	}
 
 	s.Log.Info("Order successfully saved to cache")
@@ -76,17 +128,383 @@ func (s *OrderService) SaveOrder(ctx context.Context, id string, data json.RawMe
 	return nil
 }
 
+// SaveOrders saves several orders to the database with a single batch and caches the new ones.
+// The returned error is set when the whole batch failed and none of the orders is guaranteed
+// to be stored. Otherwise the returned slice holds the outcome of every order, duplicates
+// (including repeated UIDs within the batch) are resolved like in SaveOrder.
+func (s *OrderService) SaveOrders(ctx context.Context, orders []entity.Order) ([]error, error) {
+	const op = "service.OrderService.SaveOrders"
+
+	ctx, span := tracer.Start(ctx, op, trace.WithAttributes(attribute.Int("orders.count", len(orders))))
+	defer span.End()
+
+	s.Log.Info("Attempting to save orders",
+		zap.Int("count", len(orders)),
+	)
+
+	seen := make(map[string]struct{}, len(orders))
+	unique := make([]int, 0, len(orders))
+	repeated := make([]int, 0)
+
+	for i, order := range orders {
+		if _, ok := seen[order.UID]; ok {
+			repeated = append(repeated, i)
+			continue
+		}
+		seen[order.UID] = struct{}{}
+		unique = append(unique, i)
+	}
+
+	batch := make([]entity.Order, len(unique))
+	for i, idx := range unique {
+		batch[i] = orders[idx]
+	}
+
+	inserted, err := s.Repo.AddOrders(ctx, batch)
+	if err != nil {
+		s.Log.Error("Failed to save orders to database",
+			zap.String("op", op),
+			zap.Int("count", len(batch)),
+			zap.Error(err),
+		)
+
+		return nil, tracing.Fail(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	results := make([]error, len(orders))
+	saved := 0
+
+	for i, idx := range unique {
+		order := orders[idx]
+
+		if !inserted[i] {
+			results[idx] = s.resolveDuplicate(ctx, order.UID, order.Data)
+			continue
+		}
+		saved++
+
+		s.forgetMissing(order.UID)
+
+		if err := s.Cache.Set(order.UID, withStatus(order)); err != nil && !errors.Is(err, cache.ErrKeyAlreadyExists) {
+			s.Log.Warn("Failed to save order to cache",
+				zap.String("op", op),
+				zap.String("orderID", order.UID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	for _, idx := range repeated {
+		results[idx] = s.resolveDuplicate(ctx, orders[idx].UID, orders[idx].Data)
+	}
+
+	s.Log.Info("Orders saved to database",
+		zap.Int("inserted", saved),
+		zap.Int("total", len(orders)),
+	)
+	span.SetAttributes(attribute.Int("orders.inserted", saved))
+
+	return results, nil
+}
+
+// resolveDuplicate handles an order that is already stored in the database.
+// A payload matching the stored order or any of its recorded states is acknowledged as a duplicate;
+// otherwise the conflict policy applies.
+func (s *OrderService) resolveDuplicate(ctx context.Context, id string, data json.RawMessage) error {
+	const op = "service.OrderService.resolveDuplicate"
+
+	existing, err := s.Repo.GetOrder(ctx, id)
+	if err != nil {
+		s.Log.Error("Failed to get stored order",
+			zap.String("op", op),
+			zap.String("orderID", id),
+			zap.Error(err),
+		)
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if samePayload(existing.Data, data) {
+		s.Log.Info("Duplicate order acknowledged",
+			zap.String("op", op),
+			zap.String("orderID", id),
+		)
+
+		if err := s.Cache.Set(id, withStatus(existing)); err != nil && !errors.Is(err, cache.ErrKeyAlreadyExists) {
+			s.Log.Warn("Failed to save order to cache",
+				zap.String("op", op),
+				zap.String("orderID", id),
+				zap.Error(err),
+			)
+		}
+
+		return nil
+	}
+
+	// A redelivery of a message that was superseded by an update matches an earlier state.
+	seen, err := s.seenBefore(ctx, id, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if seen {
+		s.Log.Info("Duplicate of an earlier order version acknowledged",
+			zap.String("op", op),
+			zap.String("orderID", id),
+		)
+
+		return nil
+	}
+
+	if s.Conflict != ConflictOverwrite && s.Conflict != ConflictVersion {
+		s.Log.Warn("Order already exists with a different payload",
+			zap.String("op", op),
+			zap.String("orderID", id),
+		)
+
+		return fmt.Errorf("%s: %w", op, ErrOrderConflict)
+	}
+
+	existing.Data = data
+
+	updated, err := s.Repo.UpdateOrder(ctx, existing, s.Conflict == ConflictVersion)
+	if errors.Is(err, pgdb.ErrVersionConflict) {
+		s.Log.Info("Order was modified concurrently",
+			zap.String("op", op),
+			zap.String("orderID", id),
+		)
+
+		return fmt.Errorf("%s: %w", op, ErrConcurrentUpdate)
+	}
+	if err != nil {
+		s.Log.Error("Failed to update order in database",
+			zap.String("op", op),
+			zap.String("orderID", id),
+			zap.Error(err),
+		)
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.Log.Info("Conflicting order stored",
+		zap.String("op", op),
+		zap.String("orderID", id),
+		zap.String("policy", string(s.Conflict)),
+		zap.Int("version", updated.Version),
+	)
+
+	s.refreshCache(updated)
+
+	return nil
+}
+
+// seenBefore reports whether data matches any recorded state of the order.
+func (s *OrderService) seenBefore(ctx context.Context, id string, data json.RawMessage) (bool, error) {
+	const op = "service.OrderService.seenBefore"
+
+	history, err := s.Repo.GetOrderHistory(ctx, id)
+	if err != nil {
+		s.Log.Error("Failed to get order history",
+			zap.String("op", op),
+			zap.String("orderID", id),
+			zap.Error(err),
+		)
+
+		return false, err
+	}
+
+	for _, version := range history {
+		if samePayload(version.Data, data) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// UpdateOrder moves an order to a new lifecycle status and, if data is not empty,
+// replaces its payload. The order version is incremented and the cached copy is replaced.
+// The transition is checked against the stored status; if the order is changed concurrently,
+// it is read and checked again.
+func (s *OrderService) UpdateOrder(ctx context.Context, id string, status entity.OrderStatus, data json.RawMessage) error {
+	const op = "service.OrderService.UpdateOrder"
+
+	ctx, span := tracer.Start(ctx, op, trace.WithAttributes(
+		attribute.String("order.uid", id),
+		attribute.String("order.status", string(status)),
+	))
+	defer span.End()
+
+	s.Log.Info("Attempting to update order",
+		zap.String("orderID", id),
+		zap.String("status", string(status)),
+	)
+
+	var (
+		updated entity.Order
+		err     error
+	)
+
+	for attempt := 1; ; attempt++ {
+		updated, err = s.applyUpdate(ctx, id, status, data)
+		if !errors.Is(err, pgdb.ErrVersionConflict) {
+			break
+		}
+
+		if attempt == maxUpdateAttempts {
+			s.Log.Warn("Order keeps being modified concurrently",
+				zap.String("op", op),
+				zap.String("orderID", id),
+				zap.Int("attempts", attempt),
+			)
+
+			return tracing.Fail(span, fmt.Errorf("%s: %w", op, ErrConcurrentUpdate))
+		}
+
+		s.Log.Info("Order was modified concurrently, retrying update",
+			zap.String("op", op),
+			zap.String("orderID", id),
+			zap.Int("attempt", attempt),
+		)
+	}
+	if err != nil {
+		return tracing.Fail(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	s.Log.Info("Order successfully updated",
+		zap.String("orderID", id),
+		zap.String("status", string(updated.Status)),
+		zap.Int("version", updated.Version),
+	)
+
+	s.refreshCache(updated)
+
+	return nil
+}
+
+// applyUpdate reads the order, checks the transition and updates the order if it wasn't changed meanwhile.
+// Returns pgdb.ErrVersionConflict if it was.
+func (s *OrderService) applyUpdate(ctx context.Context, id string, status entity.OrderStatus, data json.RawMessage) (entity.Order, error) {
+	const op = "service.OrderService.applyUpdate"
+
+	order, err := s.Repo.GetOrder(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgdb.ErrOrderNotFound) {
+			s.Log.Warn("Order not found",
+				zap.String("op", op),
+				zap.String("orderID", id),
+			)
+
+			return entity.Order{}, ErrOrderNotFound
+		}
+
+		s.Log.Error("Failed to get order",
+			zap.String("op", op),
+			zap.String("orderID", id),
+			zap.Error(err),
+		)
+
+		return entity.Order{}, err
+	}
+
+	if !CanTransition(order.Status, status) {
+		s.Log.Warn("Order status transition is not allowed",
+			zap.String("op", op),
+			zap.String("orderID", id),
+			zap.String("from", string(order.Status)),
+			zap.String("to", string(status)),
+		)
+
+		return entity.Order{}, fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, order.Status, status)
+	}
+
+	order.Status = status
+	if len(data) > 0 {
+		order.Data = data
+	}
+
+	updated, err := s.Repo.UpdateOrder(ctx, order, true)
+	if err != nil {
+		if errors.Is(err, pgdb.ErrVersionConflict) {
+			return entity.Order{}, err
+		}
+		if errors.Is(err, pgdb.ErrOrderNotFound) {
+			return entity.Order{}, ErrOrderNotFound
+		}
+
+		s.Log.Error("Failed to update order in database",
+			zap.String("op", op),
+			zap.String("orderID", id),
+			zap.Error(err),
+		)
+
+		return entity.Order{}, err
+	}
+
+	return updated, nil
+}
+
+// refreshCache replaces the cached copy of an order after it has changed.
+func (s *OrderService) refreshCache(order entity.Order) {
+	const op = "service.OrderService.refreshCache"
+
+	s.forgetMissing(order.UID)
+	if err := s.Cache.Replace(order.UID, withStatus(order)); err != nil {
+		s.Log.Warn("Failed to save order to cache",
+			zap.String("op", op),
+			zap.String("orderID", order.UID),
+			zap.Error(err),
+		)
+	}
+}
+
 // GetOrder retrieves an order by its ID, checking the cache first.
+// On a cache miss the order is read from the database and cached. Concurrent misses
+// of the same ID share a single database query, and IDs that weren't found are
+// remembered for a short time, so repeated lookups don't reach the database.
 func (s *OrderService) GetOrder(ctx context.Context, id string) (json.RawMessage, error) {
 	const op = "service.OrderService.GetOrder"
 
+	ctx, span := tracer.Start(ctx, op, trace.WithAttributes(attribute.String("order.uid", id)))
+	defer span.End()
+
 	s.Log.Info("Attempting to found order")
 
 	dataFromCache, found := s.Cache.Get(id)
+	span.SetAttributes(attribute.Bool("cache.hit", found))
 	if found {
-		return dataFromCache.(json.RawMessage), nil
+		return dataFromCache, nil
 	}
 
+	if s.isMissing(id) {
+		span.SetAttributes(attribute.Bool("cache.known_missing", true))
+		return nil, fmt.Errorf("%s: %w", op, ErrOrderNotFound)
+	}
+
+	// The query is shared by every waiter, so it must not be canceled with the first caller.
+	data, err, shared := s.loads.Do(id, func() (interface{}, error) {
+		return s.loadOrder(context.WithoutCancel(ctx), id)
+	})
+	span.SetAttributes(attribute.Bool("load.shared", shared))
+	if err != nil {
+		if errors.Is(err, ErrOrderNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return nil, tracing.Fail(span, fmt.Errorf("%s: %w", op, err))
+	}
+
+	s.Log.Info("Order successfully found",
+		zap.Bool("shared", shared),
+	)
+
+	return data.(json.RawMessage), nil
+}
+
+// loadOrder reads an order from the database and stores it in the cache,
+// or remembers the ID as missing if there is no such order.
+func (s *OrderService) loadOrder(ctx context.Context, id string) (json.RawMessage, error) {
+	const op = "service.OrderService.loadOrder"
+
 	order, err := s.Repo.GetOrder(ctx, id)
 	if err != nil {
 		if errors.Is(err, pgdb.ErrOrderNotFound) {
@@ -96,7 +514,11 @@ func (s *OrderService) GetOrder(ctx context.Context, id string) (json.RawMessage
 				zap.Error(err),
 			)
 
-			return nil, fmt.Errorf("%s: %w", op, ErrOrderNotFound)
+			if s.missing != nil {
+				_ = s.missing.Set(id, struct{}{})
+			}
+
+			return nil, ErrOrderNotFound
 		}
 
 		s.Log.Error("Failed to get order",
@@ -104,47 +526,202 @@ func (s *OrderService) GetOrder(ctx context.Context, id string) (json.RawMessage
 			zap.Error(err),
 		)
 
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, err
 	}
 
-	s.Log.Info("Order successfully found")
+	data := withStatus(order)
+
+	if err := s.Cache.Set(id, data); err != nil && !errors.Is(err, cache.ErrKeyAlreadyExists) {
+		s.Log.Warn("Failed to save order to cache",
+			zap.String("op", op),
+			zap.String("orderID", id),
+			zap.Error(err),
+		)
+	}
 
-	return order.Data, nil
+	return data, nil
 }
 
-func (s *OrderService) LoadOrdersToCache(ctx context.Context) error {
-	const op = "service.OrderService.GetAllOrders"
+// isMissing reports whether the ID was recently not found in the database.
+func (s *OrderService) isMissing(id string) bool {
+	if s.missing == nil {
+		return false
+	}
+
+	_, missing := s.missing.Get(id)
 
-	s.Log.Info("Attempting to fetch orders")
+	return missing
+}
+
+// forgetMissing drops the ID from the negative cache once the order is stored.
+func (s *OrderService) forgetMissing(id string) {
+	if s.missing != nil {
+		s.missing.Delete(id)
+	}
+}
 
-	orders, err := s.Repo.GetAllOrders(ctx)
+// GetOrderAsOf retrieves the state an order had at the given moment from its history.
+func (s *OrderService) GetOrderAsOf(ctx context.Context, id string, asOf time.Time) (json.RawMessage, error) {
+	const op = "service.OrderService.GetOrderAsOf"
+
+	version, err := s.Repo.GetOrderAsOf(ctx, id, asOf)
 	if err != nil {
 		if errors.Is(err, pgdb.ErrOrderNotFound) {
-			s.Log.Warn("Orders not found",
+			s.Log.Warn("Order not found",
 				zap.String("op", op),
-				zap.Error(err),
+				zap.String("orderID", id),
+				zap.Time("asOf", asOf),
 			)
 
-			return fmt.Errorf("%s: %w", op, ErrOrderNotFound)
+			return nil, fmt.Errorf("%s: %w", op, ErrOrderNotFound)
 		}
 
+		s.Log.Error("Failed to get order",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return withStatus(version.Order), nil
+}
+
+// GetOrderHistory retrieves every recorded state of an order, oldest first.
+func (s *OrderService) GetOrderHistory(ctx context.Context, id string) ([]entity.OrderVersion, error) {
+	const op = "service.OrderService.GetOrderHistory"
+
+	history, err := s.Repo.GetOrderHistory(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgdb.ErrOrderNotFound) {
+			s.Log.Warn("Order not found",
+				zap.String("op", op),
+				zap.String("orderID", id),
+			)
+
+			return nil, fmt.Errorf("%s: %w", op, ErrOrderNotFound)
+		}
+
+		s.Log.Error("Failed to get order history",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return history, nil
+}
+
+// ListOrders retrieves a page of orders matching the filter, newest first.
+// The limit defaults to DefaultListLimit and is capped at MaxListLimit.
+func (s *OrderService) ListOrders(ctx context.Context, filter entity.OrderFilter) (entity.OrderPage, error) {
+	const op = "service.OrderService.ListOrders"
+
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = DefaultListLimit
+	case filter.Limit > MaxListLimit:
+		filter.Limit = MaxListLimit
+	}
+
+	page, err := s.Repo.ListOrders(ctx, filter)
+	if err != nil {
+		s.Log.Error("Failed to list orders",
+			zap.String("op", op),
+			zap.Error(err),
+		)
+
+		return entity.OrderPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i, order := range page.Orders {
+		page.Orders[i].Data = withStatus(order)
+	}
+
+	return page, nil
+}
+
+// LoadOrdersToCache warms the cache up with the orders selected by the policy.
+// Orders are streamed from the database and cached one by one, so memory use
+// doesn't depend on the table size.
+func (s *OrderService) LoadOrdersToCache(ctx context.Context, policy WarmUpPolicy) error {
+	const op = "service.OrderService.LoadOrdersToCache"
+
+	var (
+		since time.Time
+		limit int
+	)
+
+	switch policy.Mode {
+	case WarmUpNone:
+		s.Log.Info("Cache warm-up disabled")
+
+		return nil
+	case WarmUpRecent:
+		limit = policy.Limit
+	case WarmUpPeriod:
+		since = time.Now().Add(-policy.Period)
+	case WarmUpAll:
+	}
+
+	s.Log.Info("Attempting to fetch orders",
+		zap.String("mode", string(policy.Mode)),
+	)
+
+	total, cached := 0, 0
+
+	err := s.Repo.IterateOrders(ctx, since, limit, func(order entity.Order) error {
+		total++
+		if err := s.Cache.Set(order.UID, withStatus(order)); err == nil {
+			cached++
+		}
+
+		return nil
+	})
+	if err != nil {
 		s.Log.Error("Failed to get orders",
 			zap.String("op", op),
+			zap.Int("cached", cached),
 			zap.Error(err),
 		)
 
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	counter := 0
-	for _, order := range orders {
-		err := s.Cache.Set(order.UID, order.Data)
-		if err != nil {
-			continue
-		}
-		counter++
+
+	s.Log.Info(fmt.Sprintf("Cache restored: %d/%d", cached, total))
+
+	return nil
+}
+
+// RefreshOrdersSince brings the cache up to date with the orders created or updated after since,
+// e.g. after the cache was restored from a snapshot.
+func (s *OrderService) RefreshOrdersSince(ctx context.Context, since time.Time) error {
+	const op = "service.OrderService.RefreshOrdersSince"
+
+	s.Log.Info("Attempting to fetch changed orders",
+		zap.Time("since", since),
+	)
+
+	refreshed := 0
+
+	err := s.Repo.IterateOrdersChangedSince(ctx, since, func(order entity.Order) error {
+		s.refreshCache(order)
+		refreshed++
+
+		return nil
+	})
+	if err != nil {
+		s.Log.Error("Failed to get changed orders",
+			zap.String("op", op),
+			zap.Int("refreshed", refreshed),
+			zap.Error(err),
+		)
+
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	s.Log.Info(fmt.Sprintf("Cache restored: %d/%d", len(orders), counter))
+	s.Log.Info(fmt.Sprintf("Cache refreshed: %d", refreshed))
 
 	return nil
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"go.uber.org/zap"
+	"time"
 
+	"wb-internship-l0/internal/entity"
 	"wb-internship-l0/internal/repository"
 	"wb-internship-l0/pkg/cache"
 )
@@ -12,8 +14,14 @@ import (
 // Order defines the interface for managing orders.
 type Order interface {
 	GetOrder(ctx context.Context, id string) (json.RawMessage, error)
+	GetOrderAsOf(ctx context.Context, id string, asOf time.Time) (json.RawMessage, error)
+	GetOrderHistory(ctx context.Context, id string) ([]entity.OrderVersion, error)
+	ListOrders(ctx context.Context, filter entity.OrderFilter) (entity.OrderPage, error)
 	SaveOrder(ctx context.Context, id string, data json.RawMessage) error
-	LoadOrdersToCache(ctx context.Context) error
+	SaveOrders(ctx context.Context, orders []entity.Order) ([]error, error)
+	UpdateOrder(ctx context.Context, id string, status entity.OrderStatus, data json.RawMessage) error
+	LoadOrdersToCache(ctx context.Context, policy WarmUpPolicy) error
+	RefreshOrdersSince(ctx context.Context, since time.Time) error
 }
 
 // Services aggregates all application service interfaces.
@@ -23,14 +31,18 @@ type Services struct {
 
 // ServicesDependencies holds dependencies required to create services.
 type ServicesDependencies struct {
-	Log   *zap.Logger
-	Cache cache.Cache
-	Repos *repository.Repositories
+	Log      *zap.Logger
+	Cache    cache.Cache[string, json.RawMessage]
+	Repos    *repository.Repositories
+	Conflict ConflictPolicy
+
+	// NotFoundTTL is how long nonexistent order IDs are remembered.
+	NotFoundTTL time.Duration
 }
 
 // NewServices initializes and returns a Services struct with all dependencies resolved.
 func NewServices(deps ServicesDependencies) *Services {
 	return &Services{
-		Order: NewOrderService(deps.Log, deps.Cache, deps.Repos.Order),
+		Order: NewOrderService(deps.Log, deps.Cache, deps.Repos.Order, deps.Conflict, deps.NotFoundTTL),
 	}
 }
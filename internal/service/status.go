@@ -0,0 +1,74 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"wb-internship-l0/internal/entity"
+)
+
+// transitions lists the statuses an order may move to from each status.
+// Canceled and returned orders are final.
+var transitions = map[entity.OrderStatus][]entity.OrderStatus{
+	entity.StatusCreated:    {entity.StatusAssembling, entity.StatusCanceled},
+	entity.StatusAssembling: {entity.StatusShipped, entity.StatusCanceled},
+	entity.StatusShipped:    {entity.StatusInTransit, entity.StatusDelivered, entity.StatusReturned},
+	entity.StatusInTransit:  {entity.StatusDelivered, entity.StatusReturned},
+	entity.StatusDelivered:  {entity.StatusReturned},
+	entity.StatusCanceled:   {},
+	entity.StatusReturned:   {},
+}
+
+// ParseOrderStatus converts a string to a known entity.OrderStatus.
+func ParseOrderStatus(s string) (entity.OrderStatus, error) {
+	const op = "service.ParseOrderStatus"
+
+	status := entity.OrderStatus(s)
+	if _, ok := transitions[status]; !ok {
+		return "", fmt.Errorf("%s: %w: %q", op, ErrUnknownStatus, s)
+	}
+
+	return status, nil
+}
+
+// CanTransition reports whether an order may move from one status to another.
+// Keeping the current status is always allowed.
+func CanTransition(from, to entity.OrderStatus) bool {
+	if from == to {
+		return true
+	}
+
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withStatus returns the order document as served by the API, with the current
+// lifecycle status set as the top-level "status" field.
+func withStatus(order entity.Order) json.RawMessage {
+	status := order.Status
+	if status == "" {
+		status = entity.StatusCreated
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(order.Data, &doc); err != nil || doc == nil {
+		return order.Data
+	}
+
+	encoded, err := json.Marshal(status)
+	if err != nil {
+		return order.Data
+	}
+	doc["status"] = encoded
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return order.Data
+	}
+
+	return data
+}
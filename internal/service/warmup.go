@@ -0,0 +1,39 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// WarmUpMode defines which orders are loaded into the cache at startup.
+type WarmUpMode string
+
+const (
+	// WarmUpNone leaves the cache empty, it is filled on reads.
+	WarmUpNone WarmUpMode = "none"
+	// WarmUpRecent loads the most recent WarmUpPolicy.Limit orders.
+	WarmUpRecent WarmUpMode = "recent"
+	// WarmUpPeriod loads the orders created within the last WarmUpPolicy.Period.
+	WarmUpPeriod WarmUpMode = "period"
+	// WarmUpAll loads every order.
+	WarmUpAll WarmUpMode = "all"
+)
+
+// WarmUpPolicy describes the cache warm-up.
+type WarmUpPolicy struct {
+	Mode   WarmUpMode
+	Limit  int
+	Period time.Duration
+}
+
+// ParseWarmUpMode converts a configuration value to a WarmUpMode.
+func ParseWarmUpMode(s string) (WarmUpMode, error) {
+	const op = "service.ParseWarmUpMode"
+
+	switch m := WarmUpMode(s); m {
+	case WarmUpNone, WarmUpRecent, WarmUpPeriod, WarmUpAll:
+		return m, nil
+	default:
+		return "", fmt.Errorf("%s: unknown warm-up mode %q", op, s)
+	}
+}
@@ -0,0 +1,5 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE orders_schema.order
+    ADD COLUMN IF NOT EXISTS Version INTEGER NOT NULL DEFAULT 1;
+-- +goose StatementEnd
@@ -0,0 +1,5 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE orders_schema.order
+    ADD COLUMN IF NOT EXISTS Status VARCHAR(32) NOT NULL DEFAULT 'created';
+-- +goose StatementEnd
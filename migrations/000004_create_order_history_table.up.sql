@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS orders_schema.order_history(
+   ID BIGSERIAL PRIMARY KEY,
+   OrderID VARCHAR(255) NOT NULL REFERENCES orders_schema.order(OrderID),
+   Version INTEGER NOT NULL,
+   Status VARCHAR(32) NOT NULL,
+   Data JSONB,
+   RecordedAt TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS order_history_order_recorded_idx
+    ON orders_schema.order_history(OrderID, RecordedAt);
+
+-- Existing orders are recorded as of their creation, so that as_of queries find them.
+-- The text is cast only if it carries an explicit offset, then the cast doesn't depend on TimeZone;
+-- otherwise the order is recorded as of the migration.
+INSERT INTO orders_schema.order_history(OrderID, Version, Status, Data, RecordedAt)
+SELECT OrderID, Version, Status, Data,
+    CASE
+        WHEN Data->>'date_created' ~ '^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})$'
+            THEN LEAST((Data->>'date_created')::TIMESTAMPTZ, now())
+        ELSE now()
+    END
+FROM orders_schema.order;
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Casting text to timestamptz is only stable, because it depends on the TimeZone setting.
+-- Only RFC 3339 timestamps with an explicit offset are cast, which makes the function immutable.
+-- Other values, including malformed ones, give NULL instead of failing the write.
+CREATE OR REPLACE FUNCTION orders_schema.to_timestamptz(value TEXT) RETURNS TIMESTAMPTZ
+    LANGUAGE plpgsql IMMUTABLE PARALLEL SAFE
+    AS $$
+BEGIN
+    IF value IS NULL
+        OR value !~ '^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})$' THEN
+        RETURN NULL;
+    END IF;
+
+    RETURN value::TIMESTAMPTZ;
+EXCEPTION
+    WHEN datetime_field_overflow OR invalid_datetime_format THEN
+        RETURN NULL;
+END
+$$;
+
+ALTER TABLE orders_schema.order
+    ADD COLUMN IF NOT EXISTS CustomerID TEXT GENERATED ALWAYS AS (Data->>'customer_id') STORED,
+    ADD COLUMN IF NOT EXISTS TrackNumber TEXT GENERATED ALWAYS AS (Data->>'track_number') STORED,
+    ADD COLUMN IF NOT EXISTS DeliveryService TEXT GENERATED ALWAYS AS (Data->>'delivery_service') STORED,
+    ADD COLUMN IF NOT EXISTS Locale TEXT GENERATED ALWAYS AS (Data->>'locale') STORED,
+    ADD COLUMN IF NOT EXISTS PaymentProvider TEXT GENERATED ALWAYS AS (Data->'payment'->>'provider') STORED,
+    ADD COLUMN IF NOT EXISTS DateCreated TIMESTAMPTZ
+        GENERATED ALWAYS AS (orders_schema.to_timestamptz(Data->>'date_created')) STORED;
+
+CREATE INDEX IF NOT EXISTS order_date_created_idx
+    ON orders_schema.order(DateCreated DESC NULLS LAST, OrderID DESC);
+CREATE INDEX IF NOT EXISTS order_customer_idx
+    ON orders_schema.order(CustomerID, DateCreated DESC NULLS LAST, OrderID DESC);
+CREATE INDEX IF NOT EXISTS order_track_number_idx
+    ON orders_schema.order(TrackNumber, DateCreated DESC NULLS LAST, OrderID DESC);
+CREATE INDEX IF NOT EXISTS order_delivery_service_idx
+    ON orders_schema.order(DeliveryService, DateCreated DESC NULLS LAST, OrderID DESC);
+CREATE INDEX IF NOT EXISTS order_locale_idx
+    ON orders_schema.order(Locale, DateCreated DESC NULLS LAST, OrderID DESC);
+CREATE INDEX IF NOT EXISTS order_payment_provider_idx
+    ON orders_schema.order(PaymentProvider, DateCreated DESC NULLS LAST, OrderID DESC);
+-- +goose StatementEnd
@@ -0,0 +1,5 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS order_history_recorded_idx
+    ON orders_schema.order_history(RecordedAt);
+-- +goose StatementEnd
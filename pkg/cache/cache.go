@@ -3,70 +3,175 @@ package cache
 import (
 	"errors"
 	"sync"
+	"time"
 )
 
 var (
 	ErrKeyAlreadyExists = errors.New("given key already exists")
 )
 
-// The Cache interface defines the methods required for caching.
-type Cache interface {
-	Get(key string) (interface{}, bool)
-	Set(key string, value interface{}) error
-	Delete(key string)
+// The Cache interface defines the methods required for caching values of type V by keys of type K.
+type Cache[K comparable, V any] interface {
+	Get(key K) (V, bool)
+	Set(key K, value V, opts ...SetOption) error
+	Replace(key K, value V, opts ...SetOption) error
+	Delete(key K)
+	Close()
 }
 
 // memoryCache is an in-memory implementation of the Cache interface.
-type memoryCache struct {
-	mu    sync.RWMutex
-	store map[string]item
+type memoryCache[K comparable, V any] struct {
+	mu         sync.RWMutex
+	store      map[K]item[V]
+	defaultTTL time.Duration
+	fixedTTL   bool
+	janitor    *janitor
 }
 
 // item represents the value stored in the cache
-type item struct {
-	value interface{}
+type item[V any] struct {
+	value V
+	expiration
 }
 
 // NewMemoryCache returns a new instance of memoryCache.
-func NewMemoryCache() Cache {
-	return &memoryCache{
-		store: make(map[string]item),
+func NewMemoryCache[K comparable, V any](opts ...Option) Cache[K, V] {
+	o := newOptions(opts)
+
+	m := &memoryCache[K, V]{
+		store:      make(map[K]item[V]),
+		defaultTTL: o.defaultTTL,
+		fixedTTL:   o.fixedTTL,
+	}
+
+	if o.cleanupInterval > 0 {
+		m.janitor = startJanitor(o.cleanupInterval, m.deleteExpired)
 	}
+
+	return m
 }
 
-// Get retrieves a cached value by its key. If the key is not found, it returns nil and false.
-func (m *memoryCache) Get(key string) (interface{}, bool) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// Get retrieves a cached value by its key. If the key is not found or expired, it returns nil and false.
+// Reading an entry extends its TTL unless the cache was created WithFixedTTL. To keep reads on
+// the read lock, the TTL is only extended once less than half of it is left.
+func (m *memoryCache[K, V]) Get(key K) (V, bool) {
+	var zero V
 
+	m.mu.RLock()
 	itm, exists := m.store[key]
+	m.mu.RUnlock()
+
+	if !exists {
+		return zero, false
+	}
+
+	now := time.Now()
+	if !itm.expired(now) && (m.fixedTTL || !itm.touchDue(now)) {
+		return itm.value, true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The entry may have been replaced or removed while the lock was released.
+	itm, exists = m.store[key]
 	if !exists {
-		return nil, false
+		return zero, false
+	}
+
+	now = time.Now()
+	if itm.expired(now) {
+		delete(m.store, key)
+		return zero, false
+	}
+
+	if !m.fixedTTL {
+		itm.touch(now)
+		m.store[key] = itm
 	}
 
 	return itm.value, true
 }
 
-// Set adds a value in the cache with a key.
-func (m *memoryCache) Set(key string, value interface{}) error {
+// Set adds a value in the cache with a key. An expired entry with the same key is replaced.
+func (m *memoryCache[K, V]) Set(key K, value V, opts ...SetOption) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	_, exists := m.store[key]
-	if exists {
+	now := time.Now()
+
+	existing, exists := m.store[key]
+	if exists && !existing.expired(now) {
 		return ErrKeyAlreadyExists
 	}
 
-	m.store[key] = item{
-		value: value,
+	m.store[key] = item[V]{
+		value:      value,
+		expiration: newExpiration(ttlFor(m.defaultTTL, opts), now),
+	}
+
+	return nil
+}
+
+// Replace adds a value in the cache with a key, overwriting an entry with the same key.
+func (m *memoryCache[K, V]) Replace(key K, value V, opts ...SetOption) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.store[key] = item[V]{
+		value:      value,
+		expiration: newExpiration(ttlFor(m.defaultTTL, opts), time.Now()),
 	}
 
 	return nil
 }
 
 // Delete removes a key-value pair from the cache.
-func (m *memoryCache) Delete(key string) {
+func (m *memoryCache[K, V]) Delete(key K) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	delete(m.store, key)
 }
+
+// Close stops the background janitor.
+func (m *memoryCache[K, V]) Close() {
+	m.janitor.Stop()
+}
+
+// Len returns the number of entries, including expired ones that are not removed yet.
+func (m *memoryCache[K, V]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.store)
+}
+
+// Range calls fn for every entry that is not expired until fn returns false.
+// The cache is locked for reading meanwhile, so fn must not modify it.
+func (m *memoryCache[K, V]) Range(fn func(key K, value V) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	for key, itm := range m.store {
+		if itm.expired(now) {
+			continue
+		}
+		if !fn(key, itm.value) {
+			return
+		}
+	}
+}
+
+// deleteExpired removes every expired entry.
+func (m *memoryCache[K, V]) deleteExpired() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for key, itm := range m.store {
+		if itm.expired(now) {
+			delete(m.store, key)
+		}
+	}
+}
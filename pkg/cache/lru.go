@@ -0,0 +1,236 @@
+package cache
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrEntryTooLarge = errors.New("entry exceeds cache size limit")
+)
+
+// entryOverhead is an approximate per-entry cost of the map slot, list element and key header.
+const entryOverhead = 96
+
+// lruCache is a size-bounded implementation of the Cache interface.
+// When a limit is exceeded, the least recently used entries are evicted.
+type lruCache[K comparable, V any] struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	evictions  uint64
+	order      *list.List
+	store      map[K]*list.Element
+	defaultTTL time.Duration
+	fixedTTL   bool
+	janitor    *janitor
+}
+
+// lruEntry is an element of the recency list.
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+	size  int64
+	expiration
+}
+
+// NewLRUCache returns a new instance of lruCache bounded by the number of entries
+// and by the approximate size of keys and values in bytes. A zero limit is not enforced.
+func NewLRUCache[K comparable, V any](maxEntries int, maxBytes int64, opts ...Option) Cache[K, V] {
+	o := newOptions(opts)
+
+	l := &lruCache[K, V]{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		order:      list.New(),
+		store:      make(map[K]*list.Element),
+		defaultTTL: o.defaultTTL,
+		fixedTTL:   o.fixedTTL,
+	}
+
+	if o.cleanupInterval > 0 {
+		l.janitor = startJanitor(o.cleanupInterval, l.deleteExpired)
+	}
+
+	return l
+}
+
+// Get retrieves a cached value by its key, marks it as recently used and, unless the cache
+// was created WithFixedTTL, extends its TTL.
+// If the key is not found or expired, it returns nil and false.
+func (l *lruCache[K, V]) Get(key K) (V, bool) {
+	var zero V
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, exists := l.store[key]
+	if !exists {
+		return zero, false
+	}
+
+	entry := elem.Value.(*lruEntry[K, V])
+
+	now := time.Now()
+	if entry.expired(now) {
+		l.removeElement(elem)
+		return zero, false
+	}
+
+	if !l.fixedTTL {
+		entry.touch(now)
+	}
+	l.order.MoveToFront(elem)
+
+	return entry.value, true
+}
+
+// Set adds a value in the cache with a key, evicting the least recently used entries if needed.
+// An expired entry with the same key is replaced.
+func (l *lruCache[K, V]) Set(key K, value V, opts ...SetOption) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	if elem, exists := l.store[key]; exists {
+		if !elem.Value.(*lruEntry[K, V]).expired(now) {
+			return ErrKeyAlreadyExists
+		}
+		l.removeElement(elem)
+	}
+
+	return l.insert(key, value, opts, now)
+}
+
+// Replace adds a value in the cache with a key, overwriting an entry with the same key
+// and evicting the least recently used entries if needed.
+func (l *lruCache[K, V]) Replace(key K, value V, opts ...SetOption) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, exists := l.store[key]; exists {
+		l.removeElement(elem)
+	}
+
+	return l.insert(key, value, opts, time.Now())
+}
+
+// Delete removes a key-value pair from the cache.
+func (l *lruCache[K, V]) Delete(key K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, exists := l.store[key]; exists {
+		l.removeElement(elem)
+	}
+}
+
+// Close stops the background janitor.
+func (l *lruCache[K, V]) Close() {
+	l.janitor.Stop()
+}
+
+// Range calls fn for every entry that is not expired, from the most to the least recently used,
+// until fn returns false. The cache is locked meanwhile, so fn must not modify it.
+func (l *lruCache[K, V]) Range(fn func(key K, value V) bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for elem := l.order.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*lruEntry[K, V])
+		if entry.expired(now) {
+			continue
+		}
+		if !fn(entry.key, entry.value) {
+			return
+		}
+	}
+}
+
+// deleteExpired removes every expired entry.
+func (l *lruCache[K, V]) deleteExpired() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for _, elem := range l.store {
+		if elem.Value.(*lruEntry[K, V]).expired(now) {
+			l.removeElement(elem)
+		}
+	}
+}
+
+// Len returns the number of entries, including expired ones that are not removed yet.
+func (l *lruCache[K, V]) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.order.Len()
+}
+
+// Evictions returns the number of entries evicted to stay within the limits.
+func (l *lruCache[K, V]) Evictions() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.evictions
+}
+
+// insert adds an entry for a key that isn't stored and evicts entries over the limits.
+func (l *lruCache[K, V]) insert(key K, value V, opts []SetOption, now time.Time) error {
+	size := sizeOf(key, value)
+	if l.maxBytes > 0 && size > l.maxBytes {
+		return ErrEntryTooLarge
+	}
+
+	l.store[key] = l.order.PushFront(&lruEntry[K, V]{
+		key:        key,
+		value:      value,
+		size:       size,
+		expiration: newExpiration(ttlFor(l.defaultTTL, opts), now),
+	})
+	l.bytes += size
+
+	for l.overLimit() {
+		l.removeElement(l.order.Back())
+		l.evictions++
+	}
+
+	return nil
+}
+
+func (l *lruCache[K, V]) overLimit() bool {
+	return (l.maxEntries > 0 && l.order.Len() > l.maxEntries) ||
+		(l.maxBytes > 0 && l.bytes > l.maxBytes)
+}
+
+func (l *lruCache[K, V]) removeElement(elem *list.Element) {
+	entry := l.order.Remove(elem).(*lruEntry[K, V])
+	delete(l.store, entry.key)
+	l.bytes -= entry.size
+}
+
+// sizeOf approximates the memory used by an entry.
+// Sizes of byte slices and strings are exact, other keys and values are counted as a word.
+func sizeOf(key, value any) int64 {
+	return entryOverhead + sizeOfValue(key) + sizeOfValue(value)
+}
+
+func sizeOfValue(value any) int64 {
+	switch v := value.(type) {
+	case json.RawMessage:
+		return int64(cap(v))
+	case []byte:
+		return int64(cap(v))
+	case string:
+		return int64(len(v))
+	default:
+		return 8
+	}
+}
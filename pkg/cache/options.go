@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Option configures a cache created by NewMemoryCache or NewLRUCache.
+type Option func(*options)
+
+type options struct {
+	defaultTTL      time.Duration
+	cleanupInterval time.Duration
+	fixedTTL        bool
+}
+
+// WithDefaultTTL sets the TTL of entries added without WithTTL. Zero means entries never expire.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.defaultTTL = ttl
+	}
+}
+
+// WithCleanupInterval starts a background janitor that removes expired entries
+// at the given interval. It is stopped by Close.
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.cleanupInterval = interval
+	}
+}
+
+// WithFixedTTL makes entries expire their TTL after they were set, even if they are being read.
+// By default every read extends the TTL.
+func WithFixedTTL() Option {
+	return func(o *options) {
+		o.fixedTTL = true
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// SetOption configures a single Set call.
+type SetOption func(*setOptions)
+
+type setOptions struct {
+	ttl time.Duration
+}
+
+// WithTTL sets the TTL of an entry, overriding the cache default. Zero means the entry never expires.
+func WithTTL(ttl time.Duration) SetOption {
+	return func(o *setOptions) {
+		o.ttl = ttl
+	}
+}
+
+// ttlFor returns the TTL of an entry added with the given options.
+func ttlFor(defaultTTL time.Duration, opts []SetOption) time.Duration {
+	o := setOptions{ttl: defaultTTL}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o.ttl
+}
+
+// expiration holds the TTL of an entry. Entries are kept alive while they are read:
+// every Get moves the deadline ttl into the future, so hot entries stay cached.
+type expiration struct {
+	ttl       time.Duration
+	expiresAt time.Time
+}
+
+func newExpiration(ttl time.Duration, now time.Time) expiration {
+	if ttl <= 0 {
+		return expiration{}
+	}
+
+	return expiration{ttl: ttl, expiresAt: now.Add(ttl)}
+}
+
+func (e *expiration) expired(now time.Time) bool {
+	return e.ttl > 0 && !now.Before(e.expiresAt)
+}
+
+// touchDue reports whether less than half of the TTL is left, so a read should extend it.
+func (e *expiration) touchDue(now time.Time) bool {
+	return e.ttl > 0 && e.expiresAt.Sub(now) < e.ttl/2
+}
+
+func (e *expiration) touch(now time.Time) {
+	if e.ttl > 0 {
+		e.expiresAt = now.Add(e.ttl)
+	}
+}
+
+// janitor periodically calls a cleanup function until it is stopped.
+type janitor struct {
+	once sync.Once
+	stop chan struct{}
+	done chan struct{}
+}
+
+func startJanitor(interval time.Duration, cleanup func()) *janitor {
+	j := &janitor{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(j.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				cleanup()
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+
+	return j
+}
+
+// Stop stops the janitor and waits for it to exit. It is safe to call on a nil janitor.
+func (j *janitor) Stop() {
+	if j == nil {
+		return
+	}
+
+	j.once.Do(func() {
+		close(j.stop)
+	})
+	<-j.done
+}
@@ -0,0 +1,276 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Codec converts cached values to bytes stored by a remote cache and back.
+type Codec[V any] struct {
+	Marshal   func(V) ([]byte, error)
+	Unmarshal func([]byte) (V, error)
+}
+
+// BytesCodec returns a Codec for byte slice values, e.g. json.RawMessage, that stores them as is.
+func BytesCodec[V ~[]byte]() Codec[V] {
+	return Codec[V]{
+		Marshal: func(v V) ([]byte, error) {
+			return v, nil
+		},
+		Unmarshal: func(data []byte) (V, error) {
+			return V(data), nil
+		},
+	}
+}
+
+// RedisConfig configures a cache created by NewRedisCache.
+type RedisConfig struct {
+	// Prefix is prepended to every key, so several caches can share a server.
+	Prefix string
+	// Timeout bounds every request to the server. Zero means the client timeouts are used.
+	Timeout time.Duration
+	// RetryInterval is how long the fallback cache is used after the server failed.
+	RetryInterval time.Duration
+	// OnError is called when a request to the server fails.
+	OnError func(error)
+}
+
+// redisCache is an implementation of the Cache interface backed by a server speaking
+// the Redis protocol. While the server is unavailable, the fallback cache is used.
+//
+// Deletes that didn't reach the server are kept pending and replayed before the key is
+// used again; until then the key is served by the fallback cache, never by the stale server copy.
+type redisCache[V any] struct {
+	client     *redis.Client
+	cfg        RedisConfig
+	codec      Codec[V]
+	fallback   Cache[string, V]
+	defaultTTL time.Duration
+	downUntil  atomic.Int64
+
+	pendingMu  sync.Mutex
+	pending    map[string]uint64
+	pendingGen uint64
+}
+
+// NewRedisCache returns a new instance of redisCache that takes ownership of the client.
+// Only WithDefaultTTL is applied: expired entries are removed by the server, and unlike the
+// local caches, reads don't extend the TTL. The fallback cache may be nil.
+func NewRedisCache[V any](client *redis.Client, cfg RedisConfig, codec Codec[V], fallback Cache[string, V], opts ...Option) Cache[string, V] {
+	o := newOptions(opts)
+
+	return &redisCache[V]{
+		client:     client,
+		cfg:        cfg,
+		codec:      codec,
+		fallback:   fallback,
+		defaultTTL: o.defaultTTL,
+		pending:    make(map[string]uint64),
+	}
+}
+
+// Get retrieves a cached value by its key.
+// If the key is not found, it returns the zero value and false.
+func (r *redisCache[V]) Get(key string) (V, bool) {
+	const op = "cache.redisCache.Get"
+
+	var zero V
+
+	if r.remoteDown() || !r.flushDelete(key) {
+		return r.fallbackGet(key)
+	}
+
+	ctx, cancel := r.context()
+	defer cancel()
+
+	data, err := r.client.Get(ctx, r.cfg.Prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return zero, false
+	}
+	if err != nil {
+		r.fail(fmt.Errorf("%s: %w", op, err))
+		return r.fallbackGet(key)
+	}
+
+	value, err := r.codec.Unmarshal(data)
+	if err != nil {
+		return zero, false
+	}
+
+	return value, true
+}
+
+// Set adds a value in the cache with a key.
+// Returns ErrKeyAlreadyExists if the key is already set.
+func (r *redisCache[V]) Set(key string, value V, opts ...SetOption) error {
+	const op = "cache.redisCache.Set"
+
+	if r.remoteDown() || !r.flushDelete(key) {
+		return r.fallbackSet(key, value, opts)
+	}
+
+	data, err := r.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	ctx, cancel := r.context()
+	defer cancel()
+
+	ok, err := r.client.SetNX(ctx, r.cfg.Prefix+key, data, ttlFor(r.defaultTTL, opts)).Result()
+	if err != nil {
+		r.fail(fmt.Errorf("%s: %w", op, err))
+		return r.fallbackSet(key, value, opts)
+	}
+	if !ok {
+		return ErrKeyAlreadyExists
+	}
+
+	return nil
+}
+
+// Delete removes a key-value pair from the cache and from the fallback cache.
+// If the server can't be reached, the delete stays pending until it succeeds.
+func (r *redisCache[V]) Delete(key string) {
+	if r.fallback != nil {
+		r.fallback.Delete(key)
+	}
+
+	r.markPending(key)
+	r.flushDelete(key)
+}
+
+// Replace adds a value in the cache with a key, overwriting an entry with the same key.
+// If the server can't be reached, the value is kept in the fallback cache and the server copy
+// is deleted once the server is back.
+func (r *redisCache[V]) Replace(key string, value V, opts ...SetOption) error {
+	const op = "cache.redisCache.Replace"
+
+	if r.remoteDown() || !r.flushDelete(key) {
+		r.markPending(key)
+		return r.fallbackReplace(key, value, opts)
+	}
+
+	data, err := r.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	ctx, cancel := r.context()
+	defer cancel()
+
+	if err := r.client.Set(ctx, r.cfg.Prefix+key, data, ttlFor(r.defaultTTL, opts)).Err(); err != nil {
+		r.fail(fmt.Errorf("%s: %w", op, err))
+		r.markPending(key)
+		return r.fallbackReplace(key, value, opts)
+	}
+
+	// The fallback may hold a value set during an earlier outage.
+	if r.fallback != nil {
+		r.fallback.Delete(key)
+	}
+
+	return nil
+}
+
+// markPending records that the server copy of the key must be deleted before it is used.
+func (r *redisCache[V]) markPending(key string) {
+	r.pendingMu.Lock()
+	r.pendingGen++
+	r.pending[key] = r.pendingGen
+	r.pendingMu.Unlock()
+}
+
+// flushDelete sends a pending delete of the key to the server.
+// It returns false if the delete is still pending, so the server copy must not be used.
+func (r *redisCache[V]) flushDelete(key string) bool {
+	const op = "cache.redisCache.Delete"
+
+	r.pendingMu.Lock()
+	gen, pending := r.pending[key]
+	r.pendingMu.Unlock()
+
+	if !pending {
+		return true
+	}
+	if r.remoteDown() {
+		return false
+	}
+
+	ctx, cancel := r.context()
+	defer cancel()
+
+	if err := r.client.Del(ctx, r.cfg.Prefix+key).Err(); err != nil {
+		r.fail(fmt.Errorf("%s: %w", op, err))
+		return false
+	}
+
+	// A delete issued meanwhile may have failed; it stays pending.
+	r.pendingMu.Lock()
+	if r.pending[key] == gen {
+		delete(r.pending, key)
+	}
+	r.pendingMu.Unlock()
+
+	return true
+}
+
+// Close closes the client and the fallback cache.
+func (r *redisCache[V]) Close() {
+	_ = r.client.Close()
+
+	if r.fallback != nil {
+		r.fallback.Close()
+	}
+}
+
+func (r *redisCache[V]) context() (context.Context, context.CancelFunc) {
+	if r.cfg.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithTimeout(context.Background(), r.cfg.Timeout)
+}
+
+// remoteDown reports whether the server failed less than RetryInterval ago.
+func (r *redisCache[V]) remoteDown() bool {
+	return time.Now().UnixNano() < r.downUntil.Load()
+}
+
+func (r *redisCache[V]) fail(err error) {
+	r.downUntil.Store(time.Now().Add(r.cfg.RetryInterval).UnixNano())
+
+	if r.cfg.OnError != nil {
+		r.cfg.OnError(err)
+	}
+}
+
+func (r *redisCache[V]) fallbackGet(key string) (V, bool) {
+	if r.fallback == nil {
+		var zero V
+		return zero, false
+	}
+
+	return r.fallback.Get(key)
+}
+
+func (r *redisCache[V]) fallbackSet(key string, value V, opts []SetOption) error {
+	if r.fallback == nil {
+		return nil
+	}
+
+	return r.fallback.Set(key, value, opts...)
+}
+
+func (r *redisCache[V]) fallbackReplace(key string, value V, opts []SetOption) error {
+	if r.fallback == nil {
+		return nil
+	}
+
+	return r.fallback.Replace(key, value, opts...)
+}
@@ -0,0 +1,231 @@
+package cache_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"testing"
+	"time"
+	"wb-internship-l0/pkg/cache"
+	"wb-internship-l0/pkg/cache/resptest"
+)
+
+const (
+	testPrefix        = "test:"
+	testRetryInterval = 20 * time.Millisecond
+)
+
+// newTestRedisCache returns a Redis cache backed by a resptest server, a memory fallback,
+// and a client for inspecting the server directly.
+func newTestRedisCache(t *testing.T) (cache.Cache[string, json.RawMessage], *resptest.Server, *redis.Client) {
+	t.Helper()
+
+	srv, err := resptest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+
+	c := cache.NewRedisCache[json.RawMessage](
+		redis.NewClient(&redis.Options{Addr: srv.Addr()}),
+		cache.RedisConfig{
+			Prefix:        testPrefix,
+			Timeout:       time.Second,
+			RetryInterval: testRetryInterval,
+		},
+		cache.BytesCodec[json.RawMessage](),
+		cache.NewMemoryCache[string, json.RawMessage](),
+	)
+	t.Cleanup(c.Close)
+
+	raw := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { _ = raw.Close() })
+
+	return c, srv, raw
+}
+
+func TestRedisCacheSetGet(t *testing.T) {
+	c, _, _ := newTestRedisCache(t)
+
+	if _, ok := c.Get("order"); ok {
+		t.Fatal("Get of a missing key reported a hit")
+	}
+
+	if err := c.Set("order", json.RawMessage(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := c.Get("order")
+	if !ok || string(got) != `{"v":1}` {
+		t.Fatalf("Get = %s, %v; want {\"v\":1}, true", got, ok)
+	}
+}
+
+func TestRedisCacheSetExisting(t *testing.T) {
+	c, _, _ := newTestRedisCache(t)
+
+	if err := c.Set("order", json.RawMessage(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Set("order", json.RawMessage(`{"v":2}`)); !errors.Is(err, cache.ErrKeyAlreadyExists) {
+		t.Fatalf("second Set error = %v; want ErrKeyAlreadyExists", err)
+	}
+
+	if got, _ := c.Get("order"); string(got) != `{"v":1}` {
+		t.Fatalf("Get = %s; want the first value", got)
+	}
+}
+
+func TestRedisCacheTTL(t *testing.T) {
+	c, _, raw := newTestRedisCache(t)
+
+	ttl := 50 * time.Millisecond
+	if err := c.Set("order", json.RawMessage(`{}`), cache.WithTTL(ttl)); err != nil {
+		t.Fatal(err)
+	}
+
+	pttl, err := raw.PTTL(context.Background(), testPrefix+"order").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pttl <= 0 || pttl > ttl {
+		t.Fatalf("PTTL = %v; want in (0, %v]", pttl, ttl)
+	}
+
+	time.Sleep(2 * ttl)
+
+	if _, ok := c.Get("order"); ok {
+		t.Fatal("Get of an expired key reported a hit")
+	}
+}
+
+func TestRedisCachePrefix(t *testing.T) {
+	c, _, raw := newTestRedisCache(t)
+
+	if err := c.Set("order", json.RawMessage(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if n, _ := raw.Exists(ctx, testPrefix+"order").Result(); n != 1 {
+		t.Fatalf("prefixed key exists = %d; want 1", n)
+	}
+	if n, _ := raw.Exists(ctx, "order").Result(); n != 0 {
+		t.Fatalf("unprefixed key exists = %d; want 0", n)
+	}
+
+	c.Delete("order")
+
+	if n, _ := raw.Exists(ctx, testPrefix+"order").Result(); n != 0 {
+		t.Fatalf("prefixed key exists after Delete = %d; want 0", n)
+	}
+}
+
+func TestRedisCacheFallback(t *testing.T) {
+	c, srv, raw := newTestRedisCache(t)
+
+	srv.SetDown(true)
+
+	if err := c.Set("order", json.RawMessage(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := c.Get("order")
+	if !ok || string(got) != `{"v":1}` {
+		t.Fatalf("Get while down = %s, %v; want the fallback value", got, ok)
+	}
+
+	srv.SetDown(false)
+	time.Sleep(2 * testRetryInterval)
+
+	if n, _ := raw.Exists(context.Background(), testPrefix+"order").Result(); n != 0 {
+		t.Fatalf("key written to the server while it was down")
+	}
+	if _, ok := c.Get("order"); ok {
+		t.Fatal("Get after recovery served the fallback instead of the server")
+	}
+}
+
+func TestRedisCacheDeleteDuringOutage(t *testing.T) {
+	c, srv, raw := newTestRedisCache(t)
+
+	if err := c.Set("order", json.RawMessage(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.SetDown(true)
+
+	// An update replaces the order while the server is down.
+	c.Delete("order")
+	if err := c.Set("order", json.RawMessage(`{"v":2}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.SetDown(false)
+
+	// The server still holds the old value, but it must not be served before the delete is replayed.
+	if got, ok := c.Get("order"); ok && string(got) == `{"v":1}` {
+		t.Fatal("Get right after recovery served the deleted value")
+	}
+
+	time.Sleep(2 * testRetryInterval)
+
+	if got, ok := c.Get("order"); ok && string(got) == `{"v":1}` {
+		t.Fatal("Get after recovery served the deleted value")
+	}
+	if n, _ := raw.Exists(context.Background(), testPrefix+"order").Result(); n != 0 {
+		t.Fatal("pending delete was not replayed")
+	}
+
+	if err := c.Set("order", json.RawMessage(`{"v":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.Get("order"); string(got) != `{"v":3}` {
+		t.Fatalf("Get = %s; want the value set after recovery", got)
+	}
+}
+
+func TestRedisCacheReplace(t *testing.T) {
+	c, _, _ := newTestRedisCache(t)
+
+	if err := c.Set("order", json.RawMessage(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Replace("order", json.RawMessage(`{"v":2}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := c.Get("order"); string(got) != `{"v":2}` {
+		t.Fatalf("Get = %s; want the replaced value", got)
+	}
+}
+
+func TestRedisCacheReplaceDuringOutage(t *testing.T) {
+	c, srv, raw := newTestRedisCache(t)
+
+	if err := c.Set("order", json.RawMessage(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.SetDown(true)
+
+	if err := c.Replace("order", json.RawMessage(`{"v":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.Get("order"); string(got) != `{"v":2}` {
+		t.Fatalf("Get while down = %s; want the fallback value", got)
+	}
+
+	srv.SetDown(false)
+	time.Sleep(2 * testRetryInterval)
+
+	if got, ok := c.Get("order"); ok && string(got) == `{"v":1}` {
+		t.Fatal("Get after recovery served the replaced value")
+	}
+	if n, _ := raw.Exists(context.Background(), testPrefix+"order").Result(); n != 0 {
+		t.Fatal("server copy of the replaced value was not deleted")
+	}
+}
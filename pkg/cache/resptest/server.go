@@ -0,0 +1,378 @@
+// Package resptest provides an in-process server speaking the Redis protocol (RESP),
+// so that the Redis cache can be exercised without a real server.
+//
+// Supported commands: PING, GET, SET (NX, XX, EX, PX, KEEPTTL), SETNX, DEL, EXISTS, PTTL, DBSIZE and FLUSHALL.
+package resptest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a RESP server keeping its data in memory.
+type Server struct {
+	listener net.Listener
+
+	mu    sync.Mutex
+	store map[string]entry
+	conns map[net.Conn]struct{}
+	down  bool
+
+	wg sync.WaitGroup
+}
+
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func (e entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// NewServer starts a server listening on a random local port.
+func NewServer() (*Server, error) {
+	const op = "resptest.NewServer"
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s := &Server{
+		listener: listener,
+		store:    make(map[string]entry),
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// SetDown makes the server answer every command with an error, simulating an outage.
+func (s *Server) SetDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.down = down
+}
+
+// Close stops the server and closes every connection.
+func (s *Server) Close() {
+	_ = s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+
+		_ = conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				writeError(w, err.Error())
+				_ = w.Flush()
+			}
+			return
+		}
+
+		s.exec(w, args)
+
+		// Pipelined commands are answered together.
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) exec(w *bufio.Writer, args []string) {
+	if len(args) == 0 {
+		writeError(w, "ERR empty command")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.down {
+		writeError(w, "ERR server is down")
+		return
+	}
+
+	now := time.Now()
+
+	switch cmd := strings.ToUpper(args[0]); cmd {
+	case "PING":
+		if len(args) > 1 {
+			writeBulk(w, []byte(args[1]))
+			return
+		}
+		writeSimple(w, "PONG")
+	case "GET":
+		if len(args) != 2 {
+			writeArgsError(w, cmd)
+			return
+		}
+		e, ok := s.lookup(args[1], now)
+		if !ok {
+			writeNil(w)
+			return
+		}
+		writeBulk(w, e.value)
+	case "SET":
+		s.set(w, args, now)
+	case "SETNX":
+		if len(args) != 3 {
+			writeArgsError(w, cmd)
+			return
+		}
+		if _, ok := s.lookup(args[1], now); ok {
+			writeInt(w, 0)
+			return
+		}
+		s.store[args[1]] = entry{value: []byte(args[2])}
+		writeInt(w, 1)
+	case "DEL", "EXISTS":
+		if len(args) < 2 {
+			writeArgsError(w, cmd)
+			return
+		}
+		n := 0
+		for _, key := range args[1:] {
+			if _, ok := s.lookup(key, now); ok {
+				n++
+				if cmd == "DEL" {
+					delete(s.store, key)
+				}
+			}
+		}
+		writeInt(w, int64(n))
+	case "PTTL":
+		if len(args) != 2 {
+			writeArgsError(w, cmd)
+			return
+		}
+		e, ok := s.lookup(args[1], now)
+		switch {
+		case !ok:
+			writeInt(w, -2)
+		case e.expiresAt.IsZero():
+			writeInt(w, -1)
+		default:
+			writeInt(w, e.expiresAt.Sub(now).Milliseconds())
+		}
+	case "DBSIZE":
+		n := 0
+		for key := range s.store {
+			if _, ok := s.lookup(key, now); ok {
+				n++
+			}
+		}
+		writeInt(w, int64(n))
+	case "FLUSHALL", "FLUSHDB":
+		s.store = make(map[string]entry)
+		writeSimple(w, "OK")
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+}
+
+// set handles SET key value [NX | XX] [EX seconds | PX milliseconds | KEEPTTL].
+func (s *Server) set(w *bufio.Writer, args []string, now time.Time) {
+	if len(args) < 3 {
+		writeArgsError(w, "SET")
+		return
+	}
+
+	key, value := args[1], args[2]
+
+	var (
+		nx, xx, keepTTL bool
+		ttl             time.Duration
+	)
+
+	for i := 3; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "KEEPTTL":
+			keepTTL = true
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				writeError(w, "ERR syntax error")
+				return
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				writeError(w, "ERR invalid expire time in 'set' command")
+				return
+			}
+			ttl = time.Duration(n) * time.Millisecond
+			if opt == "EX" {
+				ttl = time.Duration(n) * time.Second
+			}
+			i++
+		default:
+			writeError(w, "ERR syntax error")
+			return
+		}
+	}
+
+	prev, exists := s.lookup(key, now)
+	if (nx && exists) || (xx && !exists) {
+		writeNil(w)
+		return
+	}
+
+	e := entry{value: []byte(value)}
+	if keepTTL {
+		e.expiresAt = prev.expiresAt
+	} else if ttl > 0 {
+		e.expiresAt = now.Add(ttl)
+	}
+	s.store[key] = e
+
+	writeSimple(w, "OK")
+}
+
+// lookup returns a live entry, removing it if it is expired.
+func (s *Server) lookup(key string, now time.Time) (entry, bool) {
+	e, ok := s.store[key]
+	if !ok {
+		return entry{}, false
+	}
+	if e.expired(now) {
+		delete(s.store, key)
+		return entry{}, false
+	}
+
+	return e, true
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return nil, errors.New("ERR protocol error: expected array")
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 {
+		return nil, errors.New("ERR protocol error: invalid array length")
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errors.New("ERR protocol error: expected bulk string")
+		}
+
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, errors.New("ERR protocol error: invalid bulk length")
+		}
+
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+func writeSimple(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "+%s\r\n", s)
+}
+
+func writeError(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "-%s\r\n", s)
+}
+
+func writeArgsError(w *bufio.Writer, cmd string) {
+	writeError(w, fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+}
+
+func writeInt(w *bufio.Writer, n int64) {
+	fmt.Fprintf(w, ":%d\r\n", n)
+}
+
+func writeBulk(w *bufio.Writer, b []byte) {
+	fmt.Fprintf(w, "$%d\r\n", len(b))
+	_, _ = w.Write(b)
+	_, _ = w.WriteString("\r\n")
+}
+
+func writeNil(w *bufio.Writer) {
+	_, _ = w.WriteString("$-1\r\n")
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"hash/maphash"
+)
+
+// shardedCache is an implementation of the Cache interface that spreads keys across
+// independently locked shards, so concurrent readers and writers rarely contend for the same lock.
+type shardedCache[K comparable, V any] struct {
+	shards []Cache[K, V]
+	mask   uint64
+	hash   func(K) uint64
+}
+
+// NewShardedCache returns a new instance of shardedCache with the number of shards rounded up
+// to a power of two. Keys are assigned to shards by hash, every shard is created by newShard,
+// so limits and TTLs of bounded shards apply per shard.
+func NewShardedCache[K comparable, V any](shards int, hash func(K) uint64, newShard func() Cache[K, V]) Cache[K, V] {
+	n := ShardCount(shards)
+
+	s := &shardedCache[K, V]{
+		shards: make([]Cache[K, V], n),
+		mask:   uint64(n - 1),
+		hash:   hash,
+	}
+	for i := range s.shards {
+		s.shards[i] = newShard()
+	}
+
+	return s
+}
+
+// ShardCount returns the number of shards NewShardedCache creates when asked for the given number:
+// the smallest power of two that is not less than it.
+func ShardCount(shards int) int {
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+
+	return n
+}
+
+// StringHasher returns a hash function for string keys, seeded randomly for the process.
+func StringHasher() func(string) uint64 {
+	seed := maphash.MakeSeed()
+
+	return func(key string) uint64 {
+		return maphash.String(seed, key)
+	}
+}
+
+// Get retrieves a cached value by its key from the shard owning the key.
+func (s *shardedCache[K, V]) Get(key K) (V, bool) {
+	return s.shard(key).Get(key)
+}
+
+// Set adds a value in the cache with a key to the shard owning the key.
+func (s *shardedCache[K, V]) Set(key K, value V, opts ...SetOption) error {
+	return s.shard(key).Set(key, value, opts...)
+}
+
+// Replace adds or overwrites a value in the shard owning the key.
+func (s *shardedCache[K, V]) Replace(key K, value V, opts ...SetOption) error {
+	return s.shard(key).Replace(key, value, opts...)
+}
+
+// Delete removes a key-value pair from the cache.
+func (s *shardedCache[K, V]) Delete(key K) {
+	s.shard(key).Delete(key)
+}
+
+// Close closes every shard.
+func (s *shardedCache[K, V]) Close() {
+	for _, shard := range s.shards {
+		shard.Close()
+	}
+}
+
+// Range calls fn for every entry of the shards that support enumeration until fn returns false.
+func (s *shardedCache[K, V]) Range(fn func(key K, value V) bool) {
+	for _, shard := range s.shards {
+		ranger, ok := shard.(Ranger[K, V])
+		if !ok {
+			continue
+		}
+
+		more := true
+		ranger.Range(func(key K, value V) bool {
+			more = fn(key, value)
+			return more
+		})
+		if !more {
+			return
+		}
+	}
+}
+
+// Len returns the total number of entries of the shards that report it.
+func (s *shardedCache[K, V]) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		if sized, ok := shard.(interface{ Len() int }); ok {
+			n += sized.Len()
+		}
+	}
+
+	return n
+}
+
+// Evictions returns the total number of entries evicted by bounded shards.
+func (s *shardedCache[K, V]) Evictions() uint64 {
+	var n uint64
+	for _, shard := range s.shards {
+		if bounded, ok := shard.(interface{ Evictions() uint64 }); ok {
+			n += bounded.Evictions()
+		}
+	}
+
+	return n
+}
+
+func (s *shardedCache[K, V]) shard(key K) Cache[K, V] {
+	return s.shards[s.hash(key)&s.mask]
+}
@@ -0,0 +1,79 @@
+package cache_test
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wb-internship-l0/pkg/cache"
+)
+
+const benchKeys = 1 << 16
+
+// benchmarkMixed runs a parallel workload where writePercent of operations replace an entry
+// (like an order update) and the rest read random keys.
+func benchmarkMixed(b *testing.B, c cache.Cache[string, json.RawMessage], writePercent int) {
+	keys := make([]string, benchKeys)
+	value := json.RawMessage(`{"order_uid":"b563feb7b2b84b6test","track_number":"WBILMTESTTRACK"}`)
+	for i := range keys {
+		keys[i] = "order-" + strconv.Itoa(i)
+		_ = c.Set(keys[i], value)
+	}
+
+	var seed atomic.Uint64
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		rnd := rand.New(rand.NewPCG(seed.Add(1), 0)) //nolint:gosec // benchmark workload
+		for pb.Next() {
+			key := keys[rnd.IntN(benchKeys)]
+			if rnd.IntN(100) < writePercent {
+				_ = c.Replace(key, value)
+			} else {
+				c.Get(key)
+			}
+		}
+	})
+}
+
+func BenchmarkCache(b *testing.B) {
+	caches := []struct {
+		name string
+		new  func() cache.Cache[string, json.RawMessage]
+	}{
+		{"Memory", func() cache.Cache[string, json.RawMessage] {
+			return cache.NewMemoryCache[string, json.RawMessage]()
+		}},
+		{"MemoryTTL", func() cache.Cache[string, json.RawMessage] {
+			return cache.NewMemoryCache[string, json.RawMessage](cache.WithDefaultTTL(24 * time.Hour))
+		}},
+		{"Sharded", func() cache.Cache[string, json.RawMessage] {
+			return cache.NewShardedCache(32, cache.StringHasher(), func() cache.Cache[string, json.RawMessage] {
+				return cache.NewMemoryCache[string, json.RawMessage]()
+			})
+		}},
+		{"LRU", func() cache.Cache[string, json.RawMessage] {
+			return cache.NewLRUCache[string, json.RawMessage](benchKeys, 0)
+		}},
+		{"ShardedLRU", func() cache.Cache[string, json.RawMessage] {
+			return cache.NewShardedCache(32, cache.StringHasher(), func() cache.Cache[string, json.RawMessage] {
+				return cache.NewLRUCache[string, json.RawMessage](benchKeys/32, 0)
+			})
+		}},
+	}
+
+	for _, writes := range []int{1, 10, 50} {
+		for _, c := range caches {
+			b.Run(c.name+"/writes="+strconv.Itoa(writes)+"%", func(b *testing.B) {
+				instance := c.new()
+				defer instance.Close()
+
+				benchmarkMixed(b, instance, writes)
+			})
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+var (
+	ErrSnapshotUnsupported = errors.New("cache doesn't support snapshots")
+	ErrSnapshotCorrupted   = errors.New("cache snapshot is corrupted")
+	ErrSnapshotVersion     = errors.New("unsupported cache snapshot version")
+)
+
+// Snapshot file layout, integers are big-endian:
+//
+//	magic     [4]byte "L0CS"
+//	version   uint16
+//	watermark int64   unix nanoseconds
+//	length    uint64  payload length
+//	checksum  uint32  CRC-32C of the payload
+//	payload   gob-encoded entries
+const (
+	snapshotMagic   = "L0CS"
+	snapshotVersion = 1
+	headerSize      = 4 + 2 + 8 + 8 + 4
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Ranger is implemented by caches that can enumerate their entries.
+type Ranger[K comparable, V any] interface {
+	// Range calls fn for every live entry until fn returns false.
+	// Caches tracking recency pass the most recently used entries first.
+	Range(fn func(key K, value V) bool)
+}
+
+type snapshotEntry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// WriteSnapshot writes every entry of the cache to the file at path, replacing it atomically.
+// Entries are written from the least to the most recently used, so that LoadSnapshot,
+// which adds them in file order, leaves the hottest entries at the front of an LRU cache.
+// The watermark is stored alongside and is returned by LoadSnapshot; it should be a moment
+// before which every change is reflected in the cache. Returns the number of written entries.
+func WriteSnapshot[K comparable, V any](path string, c Cache[K, V], watermark time.Time) (int, error) {
+	const op = "cache.WriteSnapshot"
+
+	ranger, ok := c.(Ranger[K, V])
+	if !ok {
+		return 0, fmt.Errorf("%s: %w", op, ErrSnapshotUnsupported)
+	}
+
+	var entries []snapshotEntry[K, V]
+	ranger.Range(func(key K, value V) bool {
+		entries = append(entries, snapshotEntry[K, V]{Key: key, Value: value})
+		return true
+	})
+	slices.Reverse(entries)
+
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(entries); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, snapshotMagic...)
+	header = binary.BigEndian.AppendUint16(header, snapshotVersion)
+	header = binary.BigEndian.AppendUint64(header, uint64(watermark.UnixNano()))
+	header = binary.BigEndian.AppendUint64(header, uint64(payload.Len()))
+	header = binary.BigEndian.AppendUint32(header, crc32.Checksum(payload.Bytes(), crcTable))
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	_, err = w.Write(header)
+	if err == nil {
+		_, err = payload.WriteTo(w)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return len(entries), nil
+}
+
+// LoadSnapshot reads a snapshot written by WriteSnapshot into the cache.
+// Returns the snapshot watermark and the number of loaded entries.
+func LoadSnapshot[K comparable, V any](path string, c Cache[K, V]) (time.Time, int, error) {
+	const op = "cache.LoadSnapshot"
+
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return time.Time{}, 0, fmt.Errorf("%s: %w: %w", op, ErrSnapshotCorrupted, err)
+	}
+
+	if string(header[:4]) != snapshotMagic {
+		return time.Time{}, 0, fmt.Errorf("%s: %w: bad magic", op, ErrSnapshotCorrupted)
+	}
+	if version := binary.BigEndian.Uint16(header[4:6]); version != snapshotVersion {
+		return time.Time{}, 0, fmt.Errorf("%s: %w: %d", op, ErrSnapshotVersion, version)
+	}
+
+	watermark := time.Unix(0, int64(binary.BigEndian.Uint64(header[6:14])))
+	length := binary.BigEndian.Uint64(header[14:22])
+	checksum := binary.BigEndian.Uint32(header[22:26])
+
+	var payload bytes.Buffer
+	if n, err := io.Copy(&payload, io.LimitReader(r, int64(length))); err != nil || uint64(n) != length {
+		return time.Time{}, 0, fmt.Errorf("%s: %w: truncated payload", op, ErrSnapshotCorrupted)
+	}
+	if crc32.Checksum(payload.Bytes(), crcTable) != checksum {
+		return time.Time{}, 0, fmt.Errorf("%s: %w: checksum mismatch", op, ErrSnapshotCorrupted)
+	}
+
+	var entries []snapshotEntry[K, V]
+	if err := gob.NewDecoder(&payload).Decode(&entries); err != nil {
+		return time.Time{}, 0, fmt.Errorf("%s: %w: %w", op, ErrSnapshotCorrupted, err)
+	}
+
+	loaded := 0
+	for _, e := range entries {
+		if err := c.Set(e.Key, e.Value); err == nil {
+			loaded++
+		}
+	}
+
+	return watermark, loaded, nil
+}
+
+// Snapshotter periodically writes snapshots of a cache.
+type Snapshotter[K comparable, V any] struct {
+	path    string
+	cache   Cache[K, V]
+	onError func(error)
+	janitor *janitor
+}
+
+// StartSnapshots writes a snapshot of the cache to path every interval until Stop is called.
+// Errors of periodic snapshots are passed to onError.
+func StartSnapshots[K comparable, V any](c Cache[K, V], path string, interval time.Duration, onError func(error)) *Snapshotter[K, V] {
+	s := &Snapshotter[K, V]{
+		path:    path,
+		cache:   c,
+		onError: onError,
+	}
+
+	s.janitor = startJanitor(interval, func() {
+		if _, err := s.Write(); err != nil && s.onError != nil {
+			s.onError(err)
+		}
+	})
+
+	return s
+}
+
+// Write writes a snapshot right away. The watermark is taken before the entries are read.
+func (s *Snapshotter[K, V]) Write() (int, error) {
+	return WriteSnapshot(s.path, s.cache, time.Now())
+}
+
+// Stop stops periodic snapshots and writes a final one.
+func (s *Snapshotter[K, V]) Stop() (int, error) {
+	s.janitor.Stop()
+
+	return s.Write()
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"errors"
+)
+
+// TieredCache is an implementation of the Cache interface that combines a small local L1
+// with a shared L2. Reads are served from L1 when possible and fill it from L2.
+type TieredCache[K comparable, V any] struct {
+	l1       Cache[K, V]
+	l2       Cache[K, V]
+	onDelete func(K)
+}
+
+// NewTieredCache returns a new instance of TieredCache.
+// onDelete, if not nil, is called after a key is deleted, so that other instances sharing
+// the L2 can drop the key from their L1 with Invalidate.
+func NewTieredCache[K comparable, V any](l1, l2 Cache[K, V], onDelete func(K)) *TieredCache[K, V] {
+	return &TieredCache[K, V]{
+		l1:       l1,
+		l2:       l2,
+		onDelete: onDelete,
+	}
+}
+
+// Get retrieves a cached value from L1, falling back to L2.
+// Values found in L2 are copied to L1.
+func (t *TieredCache[K, V]) Get(key K) (V, bool) {
+	if value, ok := t.l1.Get(key); ok {
+		return value, true
+	}
+
+	value, ok := t.l2.Get(key)
+	if !ok {
+		return value, false
+	}
+
+	// L1 may have been filled concurrently, both values come from L2.
+	_ = t.l1.Set(key, value)
+
+	return value, true
+}
+
+// Set adds a value to both tiers. Returns ErrKeyAlreadyExists if the key is set in L2,
+// in which case L1 isn't changed. Options apply to L2 only: L1 entries live for the L1
+// default TTL, which bounds how long an instance may serve a value it missed an invalidation for.
+func (t *TieredCache[K, V]) Set(key K, value V, opts ...SetOption) error {
+	if err := t.l2.Set(key, value, opts...); err != nil {
+		return err
+	}
+
+	t.l1.Delete(key)
+	if err := t.l1.Set(key, value); err != nil && !errors.Is(err, ErrKeyAlreadyExists) {
+		return err
+	}
+
+	return nil
+}
+
+// Replace overwrites a value in L2, then removes the key from L1 and notifies other instances,
+// so every instance reads the new value from L2.
+func (t *TieredCache[K, V]) Replace(key K, value V, opts ...SetOption) error {
+	if err := t.l2.Replace(key, value, opts...); err != nil {
+		return err
+	}
+
+	t.l1.Delete(key)
+	if t.onDelete != nil {
+		t.onDelete(key)
+	}
+
+	return nil
+}
+
+// Delete removes a key from both tiers and notifies other instances.
+func (t *TieredCache[K, V]) Delete(key K) {
+	t.l1.Delete(key)
+	t.l2.Delete(key)
+
+	if t.onDelete != nil {
+		t.onDelete(key)
+	}
+}
+
+// Invalidate removes a key from L1 only. It is called when another instance deleted the key.
+func (t *TieredCache[K, V]) Invalidate(key K) {
+	t.l1.Delete(key)
+}
+
+// Len returns the number of entries in L1.
+func (t *TieredCache[K, V]) Len() int {
+	if sized, ok := t.l1.(interface{ Len() int }); ok {
+		return sized.Len()
+	}
+
+	return 0
+}
+
+// Evictions returns the number of entries evicted from L1.
+func (t *TieredCache[K, V]) Evictions() uint64 {
+	if bounded, ok := t.l1.(interface{ Evictions() uint64 }); ok {
+		return bounded.Evictions()
+	}
+
+	return 0
+}
+
+// Close closes both tiers.
+func (t *TieredCache[K, V]) Close() {
+	t.l1.Close()
+	t.l2.Close()
+}
@@ -3,21 +3,70 @@ package validation
 import (
 	"fmt"
 	"github.com/go-playground/validator/v10"
+	"reflect"
 	"strings"
 )
 
-func ValidataionError(errs validator.ValidationErrors) string {
-	var errsMsgs []string
+// FieldError describes why a single field is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
 
-	for _, err := range errs {
-		switch err.ActualTag() {
-		case "required":
-			errsMsgs = append(errsMsgs, fmt.Sprintf("%s is a required", err.Field()))
+// New returns a validator that reports fields by their json or query tag names,
+// so that errors refer to fields the way clients send them.
+func New() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "query"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+
+		return field.Name
+	})
+
+	return v
+}
 
-		default:
-			errsMsgs = append(errsMsgs, fmt.Sprintf("field %s is not valid", err.Field()))
+// Fields converts validation errors to a FieldError per invalid field.
+// Nested fields are named by their dotted path without the root struct, e.g. "delivery.email".
+func Fields(errs validator.ValidationErrors) []FieldError {
+	fields := make([]FieldError, 0, len(errs))
+
+	for _, err := range errs {
+		field := err.Field()
+		if _, path, ok := strings.Cut(err.Namespace(), "."); ok {
+			field = path
 		}
+
+		fields = append(fields, FieldError{
+			Field:   field,
+			Code:    err.Tag(),
+			Message: message(field, err),
+		})
 	}
 
-	return strings.Join(errsMsgs, ", ")
+	return fields
+}
+
+func message(field string, err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "gte", "min":
+		return fmt.Sprintf("%s must be at least %s", field, err.Param())
+	case "lte", "max":
+		return fmt.Sprintf("%s must be at most %s", field, err.Param())
+	case "email":
+		return fmt.Sprintf("%s must be a valid email address", field)
+	default:
+		return fmt.Sprintf("%s is not valid", field)
+	}
 }